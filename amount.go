@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Decimals is the number of decimal places of a coin, an Amount counts its smallest units.
+const Decimals = 8
+
+// Coin is the number of base units making one coin.
+const Coin Amount = 100000000
+
+var errInvalidAmountFormat = errors.New("amount must be a decimal number with at most 8 decimal places")
+
+// Amount is a number of base units, it's used for all balances so that signing, JSON and arithmetic agree exactly.
+type Amount int64
+
+// String formats the amount in coins with all decimal places.
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+		a = -a
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, a/Coin, Decimals, a%Coin)
+}
+
+// ParseAmount parses a number of coins like "5.67" into base units.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+	}
+	if whole == "" && fraction == "" || len(fraction) > Decimals || len(whole) > 10 {
+		return 0, errInvalidAmountFormat
+	}
+	fraction += strings.Repeat("0", Decimals-len(fraction))
+
+	var a Amount
+	for _, c := range whole + fraction {
+		if c < '0' || c > '9' {
+			return 0, errInvalidAmountFormat
+		}
+		a = a*10 + Amount(c-'0')
+	}
+	if negative {
+		a = -a
+	}
+	return a, nil
+}
+
+// addAmount adds a non-negative amount to a sum within the max supply, it fails if the amount is negative or the sum
+// would exceed the max supply. No valid amount is larger, so sums checked this way can't overflow.
+func addAmount(sum, a Amount) (Amount, bool) {
+	if a < 0 || a > Params.Policy.MaxSupply-sum {
+		return sum, false
+	}
+	return sum + a, true
+}
@@ -3,6 +3,12 @@ package main
 type Block struct {
 	PreviousHash string        `json:"previous_hash"`
 	Index        int64         `json:"index"`
+	Timestamp    int64         `json:"timestamp"`
 	Transactions []Transaction `json:"transactions"`
+	Difficulty   uint64        `json:"difficulty"`
 	Proof        uint64        `json:"proof"`
+	Signer       string        `json:"signer,omitempty"`
+	Signature    string        `json:"signature,omitempty"`
+	ChainID      string        `json:"chain_id,omitempty"`
+	ExtraData    string        `json:"extra_data,omitempty"`
 }
@@ -3,46 +3,74 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 )
 
-const MiningReward = 10
+const (
+	// MiningSender is the sender of the coinbase transaction paying the block reward to the miner.
+	MiningSender = "MINING"
+)
 
 var participants = map[string]struct{}{}
 
 type BlockChain struct {
-	NodeID           int
-	PublicKey        string
+	NodeID  int
+	Address string
+
+	// mu guards the public key of the wallet, the chain, its state, the open transactions and the peer nodes.
+	// The exported methods lock it, the unexported ones expect it to be locked.
+	// It's never held while talking to peers or sealing a block.
+	mu               sync.Mutex
+	publicKey        string
 	chain            []Block
 	openTransactions []Transaction
+	// state is the ledger state after the last block of the chain.
+	state            LedgerState
 	peerNodes        []string // TODO transform to set (map[string]struct{})
-	ResolveConflicts bool
+	resolveConflicts bool
+
+	tipMu      sync.Mutex
+	tipChanged chan struct{}
+
+	orphans OrphanPool
 }
 
 func (b *BlockChain) Chain() []Block {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	cp := make([]Block, len(b.chain))
 	copy(cp, b.chain)
 	return cp
 }
 
 func (b *BlockChain) OpenTransactions() []Transaction {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	cp := make([]Transaction, len(b.openTransactions))
 	copy(cp, b.openTransactions)
 	return cp
 }
 
 func (b *BlockChain) LoadData() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	f, err := os.Open(fmt.Sprintf("blockchain-%d.txt", b.NodeID))
 	if err != nil {
 		var pErr *os.PathError
 		if errors.As(err, &pErr) {
-			b.chain = []Block{{}}
+			b.chain = []Block{GenesisBlock}
 			b.openTransactions = make([]Transaction, 0)
+			b.state = replayChain(b.chain)
 			return
 		}
 		panic(err)
@@ -64,6 +92,10 @@ func (b *BlockChain) LoadData() {
 	if err := json.NewDecoder(&chainBuf).Decode(&b.chain); err != nil {
 		panic(err)
 	}
+	if len(b.chain) == 0 || b.chain[0].Hash() != GenesisBlock.Hash() {
+		panic("stored chain starts with a different genesis block")
+	}
+	b.state = replayChain(b.chain)
 
 	var txBuf bytes.Buffer
 	txLine, err := r.ReadSlice('\n')
@@ -94,7 +126,13 @@ func (b *BlockChain) LoadData() {
 	}
 }
 
-func (b BlockChain) SaveData() {
+func (b *BlockChain) SaveData() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.saveData()
+}
+
+func (b *BlockChain) saveData() {
 	f, err := os.OpenFile(fmt.Sprintf("blockchain-%d.txt", b.NodeID), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		panic(err)
@@ -117,76 +155,215 @@ func (b BlockChain) SaveData() {
 	}
 }
 
-func (b BlockChain) ProofOfWork() uint64 {
-	lastHash := b.GetLastBlock().Hash()
-	var proof uint64
-	for !Verification.ValidProof(b.openTransactions, lastHash, proof) {
-		proof++
+// TipChanged returns a channel which is closed once the last block of the chain changes
+// or conflicts have to be resolved, blocks sealed on top of the old tip are stale then.
+func (b *BlockChain) TipChanged() <-chan struct{} {
+	b.tipMu.Lock()
+	defer b.tipMu.Unlock()
+	if b.tipChanged == nil {
+		b.tipChanged = make(chan struct{})
+	}
+	return b.tipChanged
+}
+
+func (b *BlockChain) notifyTipChanged() {
+	b.tipMu.Lock()
+	defer b.tipMu.Unlock()
+	if b.tipChanged != nil {
+		close(b.tipChanged)
+		b.tipChanged = nil
 	}
-	return proof
 }
 
-func (b BlockChain) GetBalance() float64 {
-	if b.PublicKey == "" {
+// ResolveConflicts reports whether the chain may be behind a peer and has to be resolved before mining on.
+func (b *BlockChain) ResolveConflicts() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.resolveConflicts
+}
+
+// SetResolveConflicts marks that conflicts have to be resolved, blocks being sealed are abandoned.
+func (b *BlockChain) SetResolveConflicts() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setResolveConflicts()
+}
+
+func (b *BlockChain) setResolveConflicts() {
+	b.resolveConflicts = true
+	b.notifyTipChanged()
+}
+
+// PublicKey returns the public key of the wallet of the node, mined blocks pay it.
+func (b *BlockChain) PublicKey() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.publicKey
+}
+
+// SetPublicKey sets the public key of the wallet of the node once it's created or loaded.
+func (b *BlockChain) SetPublicKey(publicKey string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.publicKey = publicKey
+}
+
+func (b *BlockChain) GetBalance() Amount {
+	publicKey := b.PublicKey()
+	if publicKey == "" {
 		return -1
 	}
-	return b.GetBalanceWithSender(b.PublicKey)
+	return b.GetBalanceWithSender(publicKey)
 }
 
-func (b BlockChain) GetBalanceWithSender(sender string) float64 {
-	var (
-		txSender     float64
-		openTxSender float64
-		txRecipient  float64
-	)
-	for _, block := range b.chain {
-		for _, tx := range block.Transactions {
-			if tx.Sender == sender {
-				txSender += tx.Amount
-			}
-			if tx.Recipient == sender {
-				txRecipient += tx.Amount
-			}
-		}
+// GetBalanceWithSender returns the balance of sender after its open transactions.
+func (b *BlockChain) GetBalanceWithSender(sender string) Amount {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pendingBalance(sender)
+}
+
+func (b *BlockChain) pendingBalance(sender string) Amount {
+	return b.pendingState(sender).Balance(sender)
+}
+
+// Ledger returns a copy of the ledger state after the last block of the chain.
+func (b *BlockChain) Ledger() LedgerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.Clone()
+}
+
+// replayChain returns the ledger state after applying all blocks of the chain.
+func replayChain(chain []Block) LedgerState {
+	ledger := NewLedgerState()
+	for _, block := range chain {
+		_ = ledger.ApplyBlock(block)
 	}
+	return ledger
+}
+
+// pendingState returns the ledger state after the chain and the open transactions of sender.
+func (b *BlockChain) pendingState(sender string) LedgerState {
+	ledger := b.state.Clone()
 	for _, tx := range b.openTransactions {
 		if tx.Sender == sender {
-			openTxSender += tx.Amount
+			_ = ledger.ApplyTransaction(tx)
+		}
+	}
+	return ledger
+}
+
+// BlockFees returns the sum of the fees of the transactions of the block, the coinbase pays no fee.
+// It fails if a fee is negative or the fees add up to more than the max supply.
+func BlockFees(block Block) (Amount, error) {
+	var fees Amount
+	for _, tx := range block.Transactions {
+		if tx.Sender != MiningSender {
+			var ok bool
+			if fees, ok = addAmount(fees, tx.Fee); !ok {
+				return 0, errInvalidAmount
+			}
 		}
 	}
+	return fees, nil
+}
+
+// IssuedSupply returns the sum of the genesis allocations and all coinbase transactions of the chain
+// without the fees they collect.
+func (b *BlockChain) IssuedSupply() Amount {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var issued Amount
+	for _, block := range b.chain {
+		for _, tx := range block.Transactions {
+			total, _ := tx.Total()
+			switch tx.Sender {
+			case GenesisSender:
+				issued += total
+			case MiningSender:
+				fees, _ := BlockFees(block)
+				issued += total - fees
+			}
+		}
+	}
+	return issued
+}
 
-	return txRecipient - (txSender + openTxSender)
+// GetLastBlock returns a copy of the last block of the chain.
+func (b *BlockChain) GetLastBlock() *Block {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	last := b.lastBlock()
+	return &last
 }
 
-func (b BlockChain) GetLastBlock() *Block {
-	return &b.chain[len(b.chain)-1]
+func (b *BlockChain) lastBlock() Block {
+	return b.chain[len(b.chain)-1]
 }
 
-func (b *BlockChain) AddTransactionReceiving(recipient, sender string, signature string, amount float64) bool {
-	if b.PublicKey == "" {
-		return false
+// NextNonce returns the nonce of the next transaction of the sender, following its open transactions.
+func (b *BlockChain) NextNonce(sender string) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pendingState(sender).Nonce(sender)
+}
+
+// FundTransaction prepares an unsigned transaction of the sender for the ledger model of the node.
+// With the UTXO ledger it spends unspent outputs of the sender not spent by its open transactions yet,
+// otherwise it takes the next nonce of the sender.
+func (b *BlockChain) FundTransaction(tx *Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ledger := b.pendingState(tx.Sender)
+	if utxos, ok := ledger.(*UTXOSet); ok {
+		return utxos.Fund(tx)
 	}
+	tx.Nonce = ledger.Nonce(tx.Sender)
+	return nil
+}
 
-	tx := Transaction{
-		Sender:    sender,
-		Recipient: recipient,
-		Amount:    amount,
-		Signature: signature,
+// AddTransactionReceiving adds the transaction to the open transactions if it's valid after the open transactions of
+// its sender, so that its nonce follows them or it doesn't spend outputs they already spend.
+// Transactions which are not final yet are kept open until their lock time passes.
+func (b *BlockChain) AddTransactionReceiving(tx Transaction) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.publicKey == "" || tx.Sender == MiningSender || tx.Sender == GenesisSender || tx.LockTime < 0 {
+		return false
+	}
+	if containsTransaction(b.openTransactions, tx) {
+		return false
 	}
 
-	if !Verification.VerifyTransaction(tx, b.GetBalanceWithSender) {
+	if !Verification.VerifyTransaction(tx, b.pendingBalance) {
+		return false
+	}
+	if b.pendingState(tx.Sender).ApplyTransaction(tx) != nil {
 		return false
 	}
 
 	b.openTransactions = append(b.openTransactions, tx)
-	b.SaveData()
+	b.saveData()
 
 	return true
 }
 
 func (b *BlockChain) RemoveTransaction(tx Transaction) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeTransaction(tx)
+}
+
+func (b *BlockChain) removeTransaction(tx Transaction) {
+	id := tx.ID()
 	for i := range b.openTransactions {
-		if b.openTransactions[i] == tx {
+		if b.openTransactions[i].ID() == id {
 			// https://github.com/golang/go/wiki/SliceTricks#delete
 			b.openTransactions = b.openTransactions[:i+copy(b.openTransactions[i:], b.openTransactions[i+1:])]
 			return
@@ -194,19 +371,14 @@ func (b *BlockChain) RemoveTransaction(tx Transaction) {
 	}
 }
 
-func (b *BlockChain) AddTransaction(recipient, sender string, signature string, amount float64) bool {
-	if !b.AddTransactionReceiving(recipient, sender, signature, amount) {
+func (b *BlockChain) AddTransaction(tx Transaction) bool {
+	if !b.AddTransactionReceiving(tx) {
 		return false
 	}
 
-	for _, node := range b.peerNodes {
+	for _, node := range b.PeerNodes() {
 		var buf bytes.Buffer
-		_ = json.NewEncoder(&buf).Encode(map[string]interface{}{
-			"sender":    sender,
-			"recipient": recipient,
-			"amount":    amount,
-			"signature": signature,
-		})
+		_ = json.NewEncoder(&buf).Encode(tx)
 		resp, err := (&http.Client{Timeout: time.Second}).Post("http://"+node+"/broadcast-transaction", "application/json", &buf)
 		if resp != nil {
 			defer resp.Body.Close()
@@ -220,52 +392,159 @@ func (b *BlockChain) AddTransaction(recipient, sender string, signature string,
 			return false
 		}
 		if resp.StatusCode == 409 {
-			b.ResolveConflicts = true
+			b.SetResolveConflicts()
 		}
 	}
 
 	return true
 }
 
-func (b *BlockChain) MineBlock() *Block {
-	if b.PublicKey == "" {
-		return nil
+// BlockTemplate returns a prepared but unsealed block on top of the chain, paying the reward and fees to payTo.
+// Open transactions are picked by their fee rate as long as they fit into the block limits, the ones which
+// are invalid or not final on top of the chain or don't fit stay open. The open transactions of a sender are
+// queued in the order they were added, which their nonces and inputs follow, and only the head of each queue
+// is a candidate, so that every transaction is tried once. It returns false if there is no one to pay.
+func (b *BlockChain) BlockTemplate(payTo string) (Block, bool) {
+	if payTo == "" {
+		return Block{}, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	chain := b.chain
+	feeRates := make([]float64, len(b.openTransactions))
+	queues := map[string][]int{}
+	var senders []string
+	for i, tx := range b.openTransactions {
+		feeRates[i] = tx.FeeRate()
+		if len(queues[tx.Sender]) == 0 {
+			senders = append(senders, tx.Sender)
+		}
+		queues[tx.Sender] = append(queues[tx.Sender], i)
 	}
 
-	hashedBlock := b.GetLastBlock().Hash()
-	proof := b.ProofOfWork()
-
+	// The nonce of the coinbase is the index of the block, so that equal rewards still get distinct IDs.
 	rewardTx := Transaction{
-		Sender:    "MINING",
-		Recipient: b.PublicKey,
-		Amount:    MiningReward,
-	}
-
-	copiedTransactions := make([]Transaction, len(b.openTransactions))
-	copy(copiedTransactions, b.openTransactions)
-
-	for _, tx := range copiedTransactions {
-		if !(Wallet{}).VerifyTransaction(tx) {
-			return nil
+		Sender:    MiningSender,
+		Recipient: payTo,
+		Nonce:     uint64(len(b.chain)),
+	}
+	copiedTransactions := make([]Transaction, 0)
+	size := rewardTx.Size()
+	ledger := b.state.Clone()
+	for len(copiedTransactions)+1 < Params.MaxBlockTransactions {
+		next := ""
+		for _, sender := range senders {
+			if q := queues[sender]; len(q) > 0 && (next == "" || feeRates[q[0]] > feeRates[queues[next][0]]) {
+				next = sender
+			}
+		}
+		if next == "" {
+			break
+		}
+		tx := b.openTransactions[queues[next][0]]
+		queues[next] = queues[next][1:]
+		if size+tx.Size() > Params.MaxBlockSize || !tx.IsFinalAfter(chain) {
+			continue
+		}
+		if ledger.ApplyTransaction(tx) != nil {
+			continue
 		}
+		copiedTransactions = append(copiedTransactions, tx)
+		size += tx.Size()
 	}
 
+	fees, _ := BlockFees(Block{Transactions: copiedTransactions})
+	rewardTx.Amount = Params.Policy.Reward(int64(len(b.chain))) + fees
 	copiedTransactions = append(copiedTransactions, rewardTx)
 
 	block := Block{
-		PreviousHash: hashedBlock,
-		Index:        int64(len(b.chain)),
+		PreviousHash: chain[len(chain)-1].Hash(),
+		Index:        int64(len(chain)),
+		Timestamp:    NextTimestamp(chain),
 		Transactions: copiedTransactions,
-		Proof:        proof,
+	}
+	Consensus.Prepare(chain, &block)
+	return block, true
+}
+
+// MineBlock mines a block of the open transactions.
+func (b *BlockChain) MineBlock(ctx context.Context) *Block {
+	block, ok := b.BlockTemplate(b.PublicKey())
+	if !ok {
+		return nil
+	}
+	return b.MineTemplate(ctx, block)
+}
+
+// MineTemplate seals the block template, appends it to the chain and broadcasts it.
+// Mining is abandoned and nil returned when the context is cancelled, the tip of the chain changes meanwhile
+// or conflicts have to be resolved. A block whose timestamp is no longer valid once sealed is dropped as well.
+func (b *BlockChain) MineTemplate(ctx context.Context, block Block) *Block {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tipChanged := b.TipChanged()
+	go func() {
+		select {
+		case <-tipChanged:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	b.mu.Lock()
+	stale := b.lastBlock().Hash() != block.PreviousHash || b.resolveConflicts
+	chain := make([]Block, len(b.chain))
+	copy(chain, b.chain)
+	b.mu.Unlock()
+	if stale || !Consensus.Seal(ctx, chain, &block) {
+		return nil
+	}
+
+	b.mu.Lock()
+	if b.lastBlock().Hash() != block.PreviousHash || !Verification.ValidTimestamp(b.chain, block.Timestamp) || b.state.ApplyBlock(block) != nil {
+		b.mu.Unlock()
+		return nil
 	}
 	b.chain = append(b.chain, block)
-	b.openTransactions = make([]Transaction, 0)
-	b.SaveData()
+	b.notifyTipChanged()
+	for _, tx := range block.Transactions {
+		b.removeTransaction(tx)
+	}
+	b.saveData()
+	b.mu.Unlock()
+
+	if !b.BroadcastBlock(block) {
+		return nil
+	}
+	return &block
+}
+
+// SubmitBlock adds a block sealed by an external miner to the chain and broadcasts it.
+func (b *BlockChain) SubmitBlock(block Block) bool {
+	if !b.AddBlock(block) {
+		return false
+	}
+	b.ConnectOrphans()
+	b.BroadcastBlock(block)
+	return true
+}
+
+// BroadcastBlock sends the block to all peer nodes, it returns false if a peer declined it.
+func (b *BlockChain) BroadcastBlock(block Block) bool {
+	b.mu.Lock()
+	chainWork := ChainWork(b.chain).String()
+	peerNodes := make([]string, len(b.peerNodes))
+	copy(peerNodes, b.peerNodes)
+	b.mu.Unlock()
 
-	for _, node := range b.peerNodes {
+	for _, node := range peerNodes {
 		var buf bytes.Buffer
 		_ = json.NewEncoder(&buf).Encode(map[string]interface{}{
-			"block": block,
+			"block":      block,
+			"chain_work": chainWork,
+			"peer":       b.Address,
 		})
 		resp, err := (&http.Client{Timeout: time.Second}).Post("http://"+node+"/broadcast-block", "application/json", &buf)
 		if resp != nil {
@@ -277,47 +556,231 @@ func (b *BlockChain) MineBlock() *Block {
 		}
 		if resp.StatusCode == 400 || resp.StatusCode == 500 {
 			fmt.Println("Block declined, needs resolving")
-			return nil
+			return false
 		}
 	}
-
-	return &block
+	return true
 }
 
 func (b *BlockChain) AddBlock(block Block) bool {
-	if !Verification.ValidProof(block.Transactions[:len(block.Transactions)-1], block.PreviousHash, block.Proof) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.addBlock(block)
+}
+
+func (b *BlockChain) addBlock(block Block) bool {
+	if err := Verification.VerifyBlockLimits(block); err != nil {
+		return false
+	}
+	if err := Verification.VerifyCoinbase(block); err != nil {
+		return false
+	}
+	if !Verification.ValidTimestamp(b.chain, block.Timestamp) {
+		return false
+	}
+	if err := Consensus.VerifyHeader(b.chain, block); err != nil {
+		return false
+	}
+	if err := Verification.VerifyLockTimes(b.chain, block); err != nil {
 		return false
 	}
 
-	if b.GetLastBlock().Hash() != block.PreviousHash {
+	if b.lastBlock().Hash() != block.PreviousHash {
+		return false
+	}
+	if err := b.state.ApplyBlock(block); err != nil {
 		return false
 	}
 	b.chain = append(b.chain, block)
+	b.notifyTipChanged()
 
-	storedTransactions := b.OpenTransactions()
 	for _, tx := range block.Transactions {
-		for _, openTx := range storedTransactions {
-			if openTx == tx {
-				blockchain.RemoveTransaction(openTx)
+		b.removeTransaction(tx)
+	}
+	b.dropInvalidTransactions()
+
+	b.saveData()
+	return true
+}
+
+// dropInvalidTransactions removes the open transactions which became invalid on top of the chain,
+// like ones reusing a nonce or spending an output already spent by a transaction of the chain.
+func (b *BlockChain) dropInvalidTransactions() {
+	ledger := b.state.Clone()
+	filtered := make([]Transaction, 0, len(b.openTransactions))
+	for _, tx := range b.openTransactions {
+		if ledger.ApplyTransaction(tx) == nil {
+			filtered = append(filtered, tx)
+		}
+	}
+	b.openTransactions = filtered
+}
+
+// RevealedPreimage returns the first transaction of the chain claiming a contract with the preimage of the hashlock,
+// the other side of an atomic swap uses the preimage to claim its own contract.
+func (b *BlockChain) RevealedPreimage(hashlock string) (Transaction, *Block, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, block := range b.chain {
+		for _, tx := range block.Transactions {
+			if tx.Preimage == "" {
+				continue
+			}
+			if h, ok := Hashlock(tx.Preimage); ok && h == hashlock {
+				return tx, &block, true
 			}
 		}
 	}
+	return Transaction{}, nil, false
+}
 
-	b.SaveData()
-	return true
+// TransactionByID returns the transaction with the ID and the block confirming it, the block is nil while
+// the transaction is open.
+func (b *BlockChain) TransactionByID(id string) (Transaction, *Block, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := len(b.chain) - 1; i >= 0; i-- {
+		for _, tx := range b.chain[i].Transactions {
+			if tx.ID() == id {
+				block := b.chain[i]
+				return tx, &block, true
+			}
+		}
+	}
+	for _, tx := range b.openTransactions {
+		if tx.ID() == id {
+			return tx, nil, true
+		}
+	}
+	return Transaction{}, nil, false
+}
+
+// BlockByHash returns a copy of the block of the chain with the hash or nil if there is none.
+func (b *BlockChain) BlockByHash(hash string) *Block {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := b.blockIndex(hash)
+	if i < 0 {
+		return nil
+	}
+	block := b.chain[i]
+	return &block
+}
+
+// blockIndex returns the position of the block with the hash in the chain or -1 if there is none.
+func (b *BlockChain) blockIndex(hash string) int {
+	for i := len(b.chain) - 1; i >= 0; i-- {
+		if b.chain[i].Hash() == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddOrphan stores a block whose parent is unknown and requests the missing parents from the peer it came from.
+// Parents are only requested from peer nodes, blocks of other senders trigger resolving conflicts instead.
+// It returns an error and drops the block if its seal is invalid.
+func (b *BlockChain) AddOrphan(block Block, peer string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := Consensus.VerifySeal(b.chain, block); err != nil {
+		return err
+	}
+	b.orphans.Add(block, peer)
+	if !b.isPeerNode(peer) {
+		b.setResolveConflicts()
+		return nil
+	}
+	go b.fetchOrphanParents(block, peer)
+	return nil
+}
+
+func (b *BlockChain) isPeerNode(node string) bool {
+	for _, peer := range b.peerNodes {
+		if peer == node {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BlockChain) fetchOrphanParents(block Block, peer string) {
+	for i := 0; i < MaxOrphanBlocks; i++ {
+		b.mu.Lock()
+		switch {
+		case b.lastBlock().Hash() == block.PreviousHash:
+			b.connectOrphans()
+			b.mu.Unlock()
+			return
+		case b.blockIndex(block.PreviousHash) >= 0:
+			// the run forks from our chain below the tip
+			b.setResolveConflicts()
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+		if b.orphans.Has(block.PreviousHash) {
+			return
+		}
+
+		resp, err := (&http.Client{Timeout: time.Second}).Get("http://" + peer + "/block/" + block.PreviousHash)
+		if err != nil {
+			return
+		}
+		var parent Block
+		err = json.NewDecoder(resp.Body).Decode(&parent)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || parent.Hash() != block.PreviousHash {
+			return
+		}
+		if Consensus.VerifySeal(b.Chain(), parent) != nil {
+			return
+		}
+
+		b.orphans.Add(parent, peer)
+		block = parent
+	}
+}
+
+// ConnectOrphans adds the orphans building on the tip of the chain, as long as there are any.
+func (b *BlockChain) ConnectOrphans() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connectOrphans()
+}
+
+func (b *BlockChain) connectOrphans() {
+	for {
+		var connected bool
+		for _, child := range b.orphans.Children(b.lastBlock().Hash()) {
+			if !connected && b.addBlock(child) {
+				connected = true
+			}
+		}
+		if !connected {
+			return
+		}
+	}
 }
 
 func (b *BlockChain) PeerNodes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	cp := make([]string, len(b.peerNodes))
 	copy(cp, b.peerNodes)
 	return cp
 }
 
 func (b *BlockChain) Resolve() bool {
-	winnerChain := b.chain
+	winnerChain := b.Chain()
 	var replace bool
 
-	for _, node := range b.peerNodes {
+	for _, node := range b.PeerNodes() {
 		resp, err := (&http.Client{Timeout: time.Second}).Get("http://" + node + "/chain")
 		if resp != nil {
 			defer resp.Body.Close()
@@ -331,26 +794,112 @@ func (b *BlockChain) Resolve() bool {
 			continue
 		}
 
-		if len(nodeChain) > len(winnerChain) && Verification.VerifyChain(nodeChain) {
-			winnerChain = nodeChain
-			replace = true
+		if len(nodeChain) == 0 {
+			continue
+		}
+
+		if !Consensus.SelectFork(winnerChain, nodeChain) {
+			continue
 		}
+		if err := Verification.VerifyChain(nodeChain); err != nil {
+			fmt.Printf("Chain of %s rejected: %v\n", node, err)
+			continue
+		}
+		winnerChain = nodeChain
+		replace = true
 	}
-	b.ResolveConflicts = false
-	b.chain = winnerChain
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resolveConflicts = false
+	// the chain may have grown while the peers were asked
+	replace = replace && Consensus.SelectFork(b.chain, winnerChain)
 	if replace {
-		b.openTransactions = make([]Transaction, 0)
+		b.reorganize(winnerChain)
 	}
-	b.SaveData()
+	b.saveData()
 	return replace
 }
 
-func (b *BlockChain) AddPeerNode(node string) {
+// reorganize replaces the chain from the common ancestor on with the blocks of newChain.
+// Transactions of the disconnected blocks and open transactions, which aren't part of newChain
+// and are still valid on top of it, are kept as open transactions.
+func (b *BlockChain) reorganize(newChain []Block) {
+	ancestor := 0
+	for ancestor+1 < len(b.chain) && ancestor+1 < len(newChain) && b.chain[ancestor+1].Hash() == newChain[ancestor+1].Hash() {
+		ancestor++
+	}
+	disconnected := b.chain[ancestor+1:]
+	connected := newChain[ancestor+1:]
+	fmt.Printf("Reorganizing from block %d, disconnecting %d and connecting %d blocks\n", ancestor, len(disconnected), len(connected))
+
+	var confirmed []Transaction
+	for _, block := range connected {
+		confirmed = append(confirmed, block.Transactions...)
+	}
+
+	var candidates []Transaction
+	for _, block := range disconnected {
+		for _, tx := range block.Transactions {
+			if tx.Sender != MiningSender {
+				candidates = append(candidates, tx)
+			}
+		}
+	}
+	candidates = append(candidates, b.openTransactions...)
+
+	for i := len(disconnected) - 1; i >= 0; i-- {
+		b.state.UndoBlock(disconnected[i])
+	}
+	for _, block := range connected {
+		if err := b.state.ApplyBlock(block); err != nil {
+			// newChain was verified, rebuild the state in case the undone one diverged anyway
+			b.state = replayChain(newChain)
+			break
+		}
+	}
+
+	b.chain = newChain
+	b.openTransactions = make([]Transaction, 0)
+	ledger := b.state.Clone()
+	for _, tx := range candidates {
+		if containsTransaction(confirmed, tx) {
+			continue
+		}
+		if ledger.ApplyTransaction(tx) != nil {
+			continue
+		}
+		b.openTransactions = append(b.openTransactions, tx)
+	}
+	b.notifyTipChanged()
+}
+
+// AddPeerNode adds the node unless it's reachable and runs a different genesis block.
+func (b *BlockChain) AddPeerNode(node string) bool {
+	resp, err := (&http.Client{Timeout: time.Second}).Get("http://" + node + "/genesis")
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err == nil {
+		var genesis struct {
+			Hash string `json:"hash"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&genesis); err != nil || genesis.Hash != GenesisBlock.Hash() {
+			return false
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.peerNodes = append(b.peerNodes, node)
-	b.SaveData()
+	b.saveData()
+	return true
 }
 
 func (b *BlockChain) RemovePeerNode(node string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	filtered := b.peerNodes[:0]
 	for _, x := range b.peerNodes {
 		if x == node {
@@ -359,5 +908,5 @@ func (b *BlockChain) RemovePeerNode(node string) {
 	}
 
 	b.peerNodes = filtered
-	b.SaveData()
+	b.saveData()
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupRegtest switches to the regtest network, whose blocks are sealed at once, and runs the test in a temporary
+// directory as the blockchain saves its data to the working directory. The returned function restores both.
+func setupRegtest(t *testing.T) func() {
+	params, genesis, consensus, newLedgerState := Params, GenesisBlock, Consensus, NewLedgerState
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "blockchain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	Params = RegtestParams
+	GenesisBlock = Params.Genesis.Block()
+	Consensus = ProofOfWork{}
+	return func() {
+		Params, GenesisBlock, Consensus, NewLedgerState = params, genesis, consensus, newLedgerState
+		_ = os.Chdir(wd)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func newTestWallet() Wallet {
+	var w Wallet
+	w.CreateKeys()
+	return w
+}
+
+func signed(w Wallet, tx Transaction) Transaction {
+	tx.Signature = w.SignTransaction(tx)
+	return tx
+}
+
+func genesisTransaction(recipient string, amount Amount) Transaction {
+	return Transaction{Sender: GenesisSender, Recipient: recipient, Amount: amount}
+}
+
+func newTestBlockChain(w Wallet) *BlockChain {
+	b := &BlockChain{publicKey: w.PublicKey}
+	b.LoadData()
+	return b
+}
+
+func TestConcurrentMining(t *testing.T) {
+	defer setupRegtest(t)()
+	w := newTestWallet()
+	b := newTestBlockChain(w)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				b.MineBlock(context.Background())
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			template, _ := b.BlockTemplate(w.PublicKey)
+			if Consensus.Seal(context.Background(), b.Chain(), &template) {
+				b.SubmitBlock(template)
+			}
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			b.GetBalance()
+			b.IssuedSupply()
+			b.BlockByHash(b.GetLastBlock().Hash())
+		}
+	}()
+	wg.Wait()
+
+	chain := b.Chain()
+	if err := Verification.VerifyChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.GetBalance(), replayChain(chain).Balance(w.PublicKey); got != want {
+		t.Errorf("balance = %s, replaying the chain gives %s", got, want)
+	}
+}
+
+func TestResolveConflictsCancelsSeal(t *testing.T) {
+	defer setupRegtest(t)()
+	b := newTestBlockChain(newTestWallet())
+
+	template, _ := b.BlockTemplate(b.PublicKey())
+	template.Difficulty = 64
+	mined := make(chan *Block)
+	go func() {
+		mined <- b.MineTemplate(context.Background(), template)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	b.SetResolveConflicts()
+	select {
+	case block := <-mined:
+		if block != nil {
+			t.Error("block was mined although conflicts have to be resolved")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sealing wasn't cancelled")
+	}
+
+	if b.MineBlock(context.Background()) != nil {
+		t.Error("block was mined although conflicts have to be resolved")
+	}
+}
+
+func TestBlockTemplateOrdersByFeeRate(t *testing.T) {
+	defer setupRegtest(t)()
+	alice, bob, miner := newTestWallet(), newTestWallet(), newTestWallet()
+	GenesisBlock = Genesis{ChainID: "regtest", Allocations: map[string]Amount{alice.PublicKey: 1000, bob.PublicKey: 1000}}.Block()
+	b := newTestBlockChain(miner)
+
+	pay := func(w Wallet, fee Amount, nonce uint64) Transaction {
+		return signed(w, Transaction{Sender: w.PublicKey, Recipient: miner.PublicKey, Amount: 10, Fee: fee, Nonce: nonce})
+	}
+	// alice's second transaction pays the most but has to wait for her first one, which pays the least
+	transactions := []Transaction{pay(alice, 1, 0), pay(alice, 100, 1), pay(bob, 50, 0)}
+	for _, tx := range transactions {
+		if !b.AddTransactionReceiving(tx) {
+			t.Fatalf("transaction paying the fee %d wasn't added", tx.Fee)
+		}
+	}
+
+	template, ok := b.BlockTemplate(miner.PublicKey)
+	if !ok {
+		t.Fatal("no template")
+	}
+	want := []Transaction{transactions[2], transactions[0], transactions[1]}
+	got := template.Transactions[:len(template.Transactions)-1]
+	if len(got) != len(want) {
+		t.Fatalf("template has %d transactions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("transaction %d pays the fee %d, want %d", i, got[i].Fee, want[i].Fee)
+		}
+	}
+}
+
+func TestReorganizeRestoresTransactions(t *testing.T) {
+	ledgers := []struct {
+		name  string
+		state func() LedgerState
+	}{
+		{"account", func() LedgerState { return NewLedger() }},
+		{"utxo", func() LedgerState { return NewUTXOSet() }},
+	}
+	for _, ledger := range ledgers {
+		t.Run(ledger.name, func(t *testing.T) {
+			defer setupRegtest(t)()
+			NewLedgerState = ledger.state
+			alice, miner := newTestWallet(), newTestWallet()
+			GenesisBlock = Genesis{ChainID: "regtest", Allocations: map[string]Amount{alice.PublicKey: 1000}}.Block()
+			b := newTestBlockChain(miner)
+			fork := &BlockChain{NodeID: 1, publicKey: miner.PublicKey}
+			fork.LoadData()
+
+			mine := func(chain *BlockChain, transactions ...Transaction) {
+				for _, tx := range transactions {
+					if !chain.AddTransactionReceiving(tx) {
+						t.Fatalf("transaction paying %d wasn't added", tx.Amount)
+					}
+				}
+				if chain.MineBlock(context.Background()) == nil {
+					t.Fatal("mining failed")
+				}
+			}
+			pay := func(amount Amount) Transaction {
+				tx := Transaction{Sender: alice.PublicKey, Recipient: miner.PublicKey, Amount: amount, Fee: 1}
+				if err := b.FundTransaction(&tx); err != nil {
+					t.Fatal(err)
+				}
+				return signed(alice, tx)
+			}
+
+			// both chains confirm the first payment, only the shorter one the second
+			confirmed := pay(10)
+			mine(b, confirmed)
+			reverted := pay(20)
+			mine(b, reverted)
+			mine(fork, confirmed)
+			mine(fork)
+			mine(fork)
+
+			b.mu.Lock()
+			b.reorganize(fork.Chain())
+			b.mu.Unlock()
+
+			if b.GetLastBlock().Hash() != fork.GetLastBlock().Hash() {
+				t.Fatal("the chain wasn't replaced")
+			}
+			open := b.OpenTransactions()
+			if len(open) != 1 || !open[0].Equal(reverted) {
+				t.Errorf("open transactions = %v, want the reverted payment only", open)
+			}
+			if got, want := b.GetBalanceWithSender(alice.PublicKey), replayChain(fork.Chain()).Balance(alice.PublicKey)-21; got != want {
+				t.Errorf("balance after the open transactions = %s, want %s", got, want)
+			}
+		})
+	}
+}
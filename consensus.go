@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"math/big"
+)
+
+// ConsensusEngine decides how blocks are sealed, which blocks are valid and which chain wins.
+type ConsensusEngine interface {
+	// Prepare fills the consensus fields of a block about to be sealed on top of the chain.
+	Prepare(chain []Block, block *Block)
+	// Seal seals the prepared block, it gives up and returns false when the context is cancelled.
+	Seal(ctx context.Context, chain []Block, block *Block) bool
+	// VerifyHeader checks the consensus fields of a block following the chain.
+	VerifyHeader(chain []Block, block Block) error
+	// VerifySeal checks the seal of a block whose parent isn't known yet, as far as the chain allows,
+	// so that orphans can't be stored without work or authority.
+	VerifySeal(chain []Block, block Block) error
+	// Work returns how much the block adds to the weight of its chain.
+	Work(block Block) *big.Int
+	// SelectFork reports whether the remote chain should replace the local one.
+	SelectFork(local, remote []Block) bool
+}
+
+var Consensus ConsensusEngine = ProofOfWork{}
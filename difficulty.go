@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+)
+
+// NextDifficulty returns the difficulty expected from the block following the chain.
+// Every Params.DifficultyAdjustmentInterval blocks it's compared how long the last interval took to mine,
+// if it was more than twice as fast as targeted the difficulty is increased by one bit,
+// if it was more than twice as slow it's decreased by one bit.
+func NextDifficulty(chain []Block) uint64 {
+	last := chain[len(chain)-1]
+	height := int64(len(chain))
+	interval := Params.DifficultyAdjustmentInterval
+	if Params.NoRetargeting || height <= interval || height%interval != 0 {
+		return last.Difficulty
+	}
+
+	first := chain[height-interval]
+	actual := time.Duration(last.Timestamp-first.Timestamp) * time.Second
+	expected := time.Duration(interval-1) * Params.TargetBlockTime
+	switch {
+	case actual < expected/2:
+		return last.Difficulty + 1
+	case actual > expected*2 && last.Difficulty > Params.MinDifficulty:
+		return last.Difficulty - 1
+	}
+	return last.Difficulty
+}
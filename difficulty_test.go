@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// spacedChain returns a chain of n blocks of the difficulty mined the seconds apart.
+func spacedChain(n int, seconds int64, difficulty uint64) []Block {
+	chain := make([]Block, n)
+	for i := range chain {
+		chain[i] = Block{Index: int64(i), Timestamp: 1000 + int64(i)*seconds, Difficulty: difficulty}
+	}
+	return chain
+}
+
+func TestNextDifficulty(t *testing.T) {
+	defer func(params NetworkParams) { Params = params }(Params)
+	Params = MainnetParams
+	// the last interval of 10 blocks is expected to take 9 * 10 seconds
+
+	tests := []struct {
+		name          string
+		chain         []Block
+		noRetargeting bool
+		want          uint64
+	}{
+		{"more than twice as fast", spacedChain(20, 4, 8), false, 9},
+		{"twice as fast", spacedChain(20, 5, 8), false, 8},
+		{"on target", spacedChain(20, 10, 8), false, 8},
+		{"twice as slow", spacedChain(20, 20, 8), false, 8},
+		{"more than twice as slow", spacedChain(20, 21, 8), false, 7},
+		{"slow at the min difficulty", spacedChain(20, 60, 1), false, 1},
+		{"between retargets", spacedChain(21, 1, 8), false, 8},
+		{"first interval", spacedChain(10, 1, 8), false, 8},
+		{"no retargeting", spacedChain(20, 1, 8), true, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Params.NoRetargeting = tt.noRetargeting
+			if got := NextDifficulty(tt.chain); got != tt.want {
+				t.Errorf("NextDifficulty() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProofOfWorkVerifyHeaderDifficulty(t *testing.T) {
+	defer func(params NetworkParams) { Params = params }(Params)
+	Params = MainnetParams
+	chain := spacedChain(20, 4, 1)
+
+	block := Block{Index: 20, Timestamp: chain[19].Timestamp + 4, Difficulty: 1}
+	if err := (ProofOfWork{}).VerifyHeader(chain, block); !errors.Is(err, errInvalidDifficulty) {
+		t.Errorf("VerifyHeader() = %v, want %v", err, errInvalidDifficulty)
+	}
+	block.Difficulty = 2
+	if !(ProofOfWork{}).Seal(context.Background(), chain, &block) {
+		t.Fatal("sealing failed")
+	}
+	if err := (ProofOfWork{}).VerifyHeader(chain, block); err != nil {
+		t.Errorf("VerifyHeader() = %v, want the retargeted difficulty to be valid", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// GenesisSender is the sender of the genesis transactions allocating the premine.
+const GenesisSender = "GENESIS"
+
+// Genesis describes the first block of a network, nodes only talk to peers sharing the same genesis block.
+type Genesis struct {
+	ChainID     string            `json:"chain_id"`
+	Difficulty  uint64            `json:"difficulty"`
+	Timestamp   int64             `json:"timestamp"`
+	ExtraData   string            `json:"extra_data"`
+	Allocations map[string]Amount `json:"allocations"`
+}
+
+// GenesisBlock is the first block every chain of this node has to start with.
+var GenesisBlock = Params.Genesis.Block()
+
+// LoadGenesis reads the genesis from a JSON file, the genesis of the network is used when no file is given.
+func LoadGenesis(path string) Genesis {
+	if path == "" {
+		return Params.Genesis
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var g Genesis
+	if err := json.NewDecoder(f).Decode(&g); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Supply returns the sum of all allocations, it reports false if one is negative or they exceed the max supply.
+func (g Genesis) Supply() (Amount, bool) {
+	var supply Amount
+	for _, amount := range g.Allocations {
+		var ok bool
+		if supply, ok = addAmount(supply, amount); !ok {
+			return 0, false
+		}
+	}
+	return supply, true
+}
+
+// Block returns the genesis block, the allocations are paid by transactions ordered by the recipient.
+func (g Genesis) Block() Block {
+	recipients := make([]string, 0, len(g.Allocations))
+	for recipient := range g.Allocations {
+		recipients = append(recipients, recipient)
+	}
+	sort.Strings(recipients)
+
+	transactions := make([]Transaction, 0, len(recipients))
+	for _, recipient := range recipients {
+		transactions = append(transactions, Transaction{
+			Sender:    GenesisSender,
+			Recipient: recipient,
+			Amount:    g.Allocations[recipient],
+		})
+	}
+
+	return Block{
+		Index:        0,
+		Timestamp:    g.Timestamp,
+		Transactions: transactions,
+		Difficulty:   g.Difficulty,
+		ChainID:      g.ChainID,
+		ExtraData:    g.ExtraData,
+	}
+}
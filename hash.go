@@ -4,6 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/bits"
 )
 
 func HashString256(s string) string {
@@ -11,6 +14,32 @@ func HashString256(s string) string {
 	return hex.EncodeToString(h[:])
 }
 
+// HashMeetsDifficulty reports whether the hex encoded hash starts with at least difficulty zero bits.
+func HashMeetsDifficulty(h string, difficulty uint64) bool {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return false
+	}
+
+	var zeros uint64
+	for _, x := range b {
+		zeros += uint64(bits.LeadingZeros8(x))
+		if x != 0 {
+			break
+		}
+	}
+	return zeros >= difficulty
+}
+
+// DifficultyTarget returns the hex encoded highest hash meeting the difficulty.
+func DifficultyTarget(difficulty uint64) string {
+	if difficulty > 256 {
+		difficulty = 256
+	}
+	target := new(big.Int).Lsh(big.NewInt(1), uint(256-difficulty))
+	return fmt.Sprintf("%064x", target.Sub(target, big.NewInt(1)))
+}
+
 func (b Block) Hash() string {
 	j, _ := json.Marshal(b)
 	return HashString256(string(j))
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	errInvalidHTLC     = errors.New("htlc needs a recipient, a SHA-256 hashlock, a positive amount within the max supply and an expiry block index")
+	errUnknownContract = errors.New("contract is unknown or already redeemed")
+	errInvalidRedeem   = errors.New("transaction doesn't redeem the whole contract")
+	errInvalidPreimage = errors.New("preimage doesn't match the hashlock or is revealed by someone else than the recipient")
+	errEarlyRefund     = errors.New("refund isn't sent by the contract sender or locked until the contract expiry")
+	errLateClaim       = errors.New("contract expired, only its sender can refund it")
+)
+
+// HTLC is a hash time-locked contract, a transaction carrying it locks Amount of its sender.
+// The Recipient claims the amount by revealing the preimage of the Hashlock in a block before the one with
+// index Expiry, from that block on only the sender can refund it instead.
+type HTLC struct {
+	Recipient string `json:"recipient"`
+	Hashlock  string `json:"hashlock"`
+	Expiry    int64  `json:"expiry"`
+	Amount    Amount `json:"amount"`
+}
+
+func (h HTLC) Validate() error {
+	if h.Recipient == "" || h.Amount <= 0 || h.Amount > Params.Policy.MaxSupply || h.Expiry <= 0 || h.Expiry >= LockTimeThreshold {
+		return errInvalidHTLC
+	}
+	if b, err := hex.DecodeString(h.Hashlock); err != nil || len(b) != sha256.Size || h.Hashlock != hex.EncodeToString(b) {
+		return errInvalidHTLC
+	}
+	return nil
+}
+
+// Contract is a locked HTLC, it's identified by the ID of the transaction creating it.
+type Contract struct {
+	HTLC
+	Sender string `json:"sender"`
+}
+
+// Hashlock returns the hex encoded SHA-256 hash of the hex encoded preimage.
+func Hashlock(preimage string) (string, bool) {
+	b, err := hex.DecodeString(preimage)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), true
+}
+
+// verifyRedeem checks that the transaction, mined in the block with the index, claims the contract with its preimage
+// before the expiry or refunds it after. The whole amount has to be redeemed, the fee is paid from it.
+func verifyRedeem(tx Transaction, c Contract, index int64) error {
+	if cost, err := tx.Cost(); tx.HTLC != nil || len(tx.Inputs) > 0 || err != nil || cost != c.Amount {
+		return errInvalidRedeem
+	}
+	if tx.Preimage != "" {
+		if hashlock, ok := Hashlock(tx.Preimage); !ok || hashlock != c.Hashlock || tx.Sender != c.Recipient {
+			return errInvalidPreimage
+		}
+		if index >= c.Expiry {
+			return errLateClaim
+		}
+		return nil
+	}
+	if tx.Sender != c.Sender || tx.LockTime < c.Expiry || tx.LockTime >= LockTimeThreshold {
+		return errEarlyRefund
+	}
+	return nil
+}
+
+// verifyContract checks the HTLC created or the contract redeemed by the transaction, mined in the block with the
+// index, against the locked contracts.
+func verifyContract(tx Transaction, contracts map[string]Contract, index int64) error {
+	if tx.HTLC != nil {
+		if err := tx.HTLC.Validate(); err != nil {
+			return err
+		}
+	}
+	if tx.Contract == "" {
+		if tx.Preimage != "" {
+			return errInvalidRedeem
+		}
+		return nil
+	}
+	c, ok := contracts[tx.Contract]
+	if !ok {
+		return errUnknownContract
+	}
+	return verifyRedeem(tx, c, index)
+}
+
+// applyContract moves the contract redeemed by the transaction to the redeemed ones and locks the HTLC it creates.
+func applyContract(tx Transaction, contracts, redeemed map[string]Contract) {
+	if tx.Contract != "" {
+		redeemed[tx.Contract] = contracts[tx.Contract]
+		delete(contracts, tx.Contract)
+	}
+	if tx.HTLC != nil {
+		contracts[tx.ID()] = Contract{HTLC: *tx.HTLC, Sender: tx.Sender}
+	}
+}
+
+// undoContract reverts applyContract, it unlocks the HTLC created by the transaction and restores the redeemed contract.
+func undoContract(tx Transaction, contracts, redeemed map[string]Contract) {
+	if tx.HTLC != nil {
+		delete(contracts, tx.ID())
+	}
+	if tx.Contract != "" {
+		contracts[tx.Contract] = redeemed[tx.Contract]
+		delete(redeemed, tx.Contract)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errInvalidSignature  = errors.New("transaction signature is invalid")
+	errInsufficientFunds = errors.New("sender has insufficient funds")
+	errInvalidAmount     = errors.New("transaction amount or fee is negative or exceeds the max supply")
+	errInvalidOutput     = errors.New("output has no recipient, a negative amount or pays more than the max supply")
+	errInvalidNonce      = errors.New("transaction nonce is out of sequence")
+	errUnexpectedInputs  = errors.New("transaction inputs are only spent by the UTXO ledger")
+	errInvalidVote       = errors.New("vote is neither add nor remove")
+)
+
+// LedgerState is the state transactions are validated against. The blockchain keeps the state of its chain,
+// blocks are applied when connected and undone when disconnected.
+type LedgerState interface {
+	// ApplyTransaction checks the transaction against the state and applies it, the state is unchanged on error.
+	// The transaction is checked as if mined in the block following the last applied block.
+	ApplyTransaction(tx Transaction) error
+	// ApplyBlock applies all transactions of the block in order, the state is unchanged if one is invalid.
+	ApplyBlock(block Block) error
+	// UndoBlock reverts the block, which has to be the last block applied.
+	UndoBlock(block Block)
+	// Clone returns a copy of the state to apply transactions to, it can't undo blocks applied before.
+	Clone() LedgerState
+	Balance(account string) Amount
+	// Nonce returns the nonce the next transaction sent by the account has to carry.
+	Nonce(account string) uint64
+	// Contract returns the locked HTLC with the ID, it's gone once claimed or refunded.
+	Contract(id string) (Contract, bool)
+}
+
+// NewLedgerState creates an empty state of the ledger model the node runs, account balances by default.
+var NewLedgerState = func() LedgerState {
+	return NewLedger()
+}
+
+// Ledger tracks the balances and nonces of all accounts while transactions are replayed in chain order.
+type Ledger struct {
+	balances  map[string]Amount
+	nonces    map[string]uint64
+	contracts map[string]Contract
+	// redeemed keeps the contracts redeemed since the ledger was created, to restore them when undoing blocks.
+	redeemed map[string]Contract
+	// index is the index of the block the transactions applied next are mined in.
+	index int64
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		balances:  map[string]Amount{},
+		nonces:    map[string]uint64{},
+		contracts: map[string]Contract{},
+		redeemed:  map[string]Contract{},
+	}
+}
+
+func (l *Ledger) Clone() LedgerState {
+	c := NewLedger()
+	for account, balance := range l.balances {
+		c.balances[account] = balance
+	}
+	for account, nonce := range l.nonces {
+		c.nonces[account] = nonce
+	}
+	for id, contract := range l.contracts {
+		c.contracts[id] = contract
+	}
+	c.index = l.index
+	return c
+}
+
+func (l *Ledger) Balance(account string) Amount {
+	return l.balances[account]
+}
+
+func (l *Ledger) Nonce(account string) uint64 {
+	return l.nonces[account]
+}
+
+func (l *Ledger) Contract(id string) (Contract, bool) {
+	c, ok := l.contracts[id]
+	return c, ok
+}
+
+// ApplyTransaction checks the signature of the transaction, the nonce and the balance of its sender and applies it.
+// A transaction redeeming a contract is paid from the contract instead of the balance of its sender.
+// Coinbase and genesis transactions are credited without any checks, they have to be validated with their block.
+func (l *Ledger) ApplyTransaction(tx Transaction) error {
+	if tx.Sender != MiningSender && tx.Sender != GenesisSender {
+		if tx.Amount < 0 || tx.Fee < 0 {
+			return errInvalidAmount
+		}
+		if err := verifyOutputs(tx); err != nil {
+			return err
+		}
+		cost, err := tx.Cost()
+		if err != nil {
+			return err
+		}
+		if err := verifyVote(tx); err != nil {
+			return err
+		}
+		if len(tx.Inputs) > 0 {
+			return errUnexpectedInputs
+		}
+		if !(Wallet{}).VerifyTransaction(tx) {
+			return errInvalidSignature
+		}
+		if tx.Nonce != l.nonces[tx.Sender] {
+			return errInvalidNonce
+		}
+		if err := verifyContract(tx, l.contracts, l.index); err != nil {
+			return err
+		}
+		if tx.Contract == "" {
+			if l.balances[tx.Sender] < cost {
+				return errInsufficientFunds
+			}
+			l.balances[tx.Sender] -= cost
+		}
+		applyContract(tx, l.contracts, l.redeemed)
+		l.nonces[tx.Sender]++
+	}
+	for _, out := range tx.Payouts() {
+		l.balances[out.Recipient] += out.Amount
+	}
+	return nil
+}
+
+// verifyOutputs checks that a transaction paying several outputs doesn't pay Recipient as well, that every output
+// has a recipient and a non-negative amount and that the payouts don't add up to more than the max supply.
+// The Recipient of a vote is the candidate, a vote may pay change outputs nonetheless.
+func verifyOutputs(tx Transaction) error {
+	if len(tx.Outputs) > 0 && (tx.Amount != 0 || tx.Recipient != "" && tx.Vote == "") {
+		return errInvalidOutput
+	}
+	for _, out := range tx.Outputs {
+		if out.Recipient == "" {
+			return errInvalidOutput
+		}
+	}
+	_, err := tx.Total()
+	return err
+}
+
+// verifyVote checks that a transaction voting on a signer votes to add or to remove it.
+func verifyVote(tx Transaction) error {
+	if tx.Vote != "" && tx.Vote != VoteAdd && tx.Vote != VoteRemove {
+		return errInvalidVote
+	}
+	return nil
+}
+
+func (l *Ledger) ApplyBlock(block Block) error {
+	index := l.index
+	l.index = block.Index
+	for i, tx := range block.Transactions {
+		if err := l.ApplyTransaction(tx); err != nil {
+			l.undoTransactions(block.Transactions[:i])
+			l.index = index
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	l.index = block.Index + 1
+	return nil
+}
+
+func (l *Ledger) UndoBlock(block Block) {
+	l.undoTransactions(block.Transactions)
+	l.index = block.Index
+}
+
+// undoTransactions reverts the applied transactions in reverse order.
+func (l *Ledger) undoTransactions(transactions []Transaction) {
+	for i := len(transactions) - 1; i >= 0; i-- {
+		tx := transactions[i]
+		for _, out := range tx.Payouts() {
+			l.balances[out.Recipient] -= out.Amount
+			if l.balances[out.Recipient] == 0 {
+				delete(l.balances, out.Recipient)
+			}
+		}
+		if tx.Sender == MiningSender || tx.Sender == GenesisSender {
+			continue
+		}
+		l.nonces[tx.Sender]--
+		if l.nonces[tx.Sender] == 0 {
+			delete(l.nonces, tx.Sender)
+		}
+		undoContract(tx, l.contracts, l.redeemed)
+		if tx.Contract == "" {
+			cost, _ := tx.Cost()
+			l.balances[tx.Sender] += cost
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLedgerApplyTransaction(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	// alice is funded with 200 and has sent one transaction, her next nonce is 1 and 100 are left
+	first := signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 50, Fee: 50})
+	pay := func(amount, fee Amount, nonce uint64) Transaction {
+		return signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: amount, Fee: fee, Nonce: nonce})
+	}
+
+	tests := []struct {
+		name string
+		tx   Transaction
+		err  error
+	}{
+		{"valid", pay(60, 40, 1), nil},
+		{"fee only", pay(0, 100, 1), nil},
+		{"fee exceeds balance", pay(60, 41, 1), errInsufficientFunds},
+		{"negative fee", pay(200, -100, 1), errInvalidAmount},
+		{"fee overflowing", pay(10, math.MaxInt64, 1), errInvalidAmount},
+		{"htlc above max supply", signed(alice, Transaction{Sender: alice.PublicKey, Nonce: 1, HTLC: &HTLC{Recipient: bob.PublicKey, Amount: math.MaxInt64}}), errInvalidAmount},
+		{"negative amount", pay(-10, 1, 1), errInvalidAmount},
+		{"replayed transaction", first, errInvalidNonce},
+		{"stale nonce", pay(10, 1, 0), errInvalidNonce},
+		{"future nonce", pay(10, 1, 2), errInvalidNonce},
+		{"forged signature", signed(bob, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Nonce: 1}), errInvalidSignature},
+		{"invalid vote", signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Nonce: 1, Vote: "promote"}), errInvalidVote},
+		{"inputs", signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Nonce: 1, Inputs: []OutPoint{{TxID: "x"}}}), errUnexpectedInputs},
+		{"outputs and recipient", signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Nonce: 1, Outputs: []Output{{Recipient: bob.PublicKey, Amount: 1}}}), errInvalidOutput},
+		{"output above max supply", signed(alice, Transaction{Sender: alice.PublicKey, Nonce: 1, Outputs: []Output{{bob.PublicKey, Params.Policy.MaxSupply + 1}}}), errInvalidOutput},
+		{"outputs overflowing", signed(alice, Transaction{Sender: alice.PublicKey, Nonce: 1, Outputs: []Output{{bob.PublicKey, math.MaxInt64}, {bob.PublicKey, math.MaxInt64}, {alice.PublicKey, 3}}}), errInvalidOutput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLedger()
+			if err := l.ApplyBlock(Block{Transactions: []Transaction{genesisTransaction(alice.PublicKey, 200)}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.ApplyTransaction(first); err != nil {
+				t.Fatal(err)
+			}
+
+			err := l.ApplyTransaction(tt.tx)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ApplyTransaction() = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				if l.Balance(alice.PublicKey) != 100 || l.Nonce(alice.PublicKey) != 1 {
+					t.Errorf("invalid transaction changed the ledger")
+				}
+				return
+			}
+			cost, _ := tt.tx.Cost()
+			if want := 100 - cost; l.Balance(alice.PublicKey) != want {
+				t.Errorf("balance = %d, want %d", l.Balance(alice.PublicKey), want)
+			}
+			if l.Nonce(alice.PublicKey) != 2 {
+				t.Errorf("nonce = %d, want 2", l.Nonce(alice.PublicKey))
+			}
+		})
+	}
+}
+
+func TestLedgerRedeemContract(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	preimage := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	hashlock, _ := Hashlock(preimage)
+	lock := signed(alice, Transaction{
+		Sender: alice.PublicKey,
+		Fee:    1,
+		HTLC:   &HTLC{Recipient: bob.PublicKey, Hashlock: hashlock, Expiry: 3, Amount: 100},
+	})
+	claim := func(preimage string) Transaction {
+		return signed(bob, Transaction{Sender: bob.PublicKey, Recipient: bob.PublicKey, Amount: 99, Fee: 1, Contract: lock.ID(), Preimage: preimage})
+	}
+	refund := func(lockTime int64) Transaction {
+		return signed(alice, Transaction{Sender: alice.PublicKey, Recipient: alice.PublicKey, Amount: 99, Fee: 1, Nonce: 1, LockTime: lockTime, Contract: lock.ID()})
+	}
+
+	tests := []struct {
+		name  string
+		index int64
+		tx    Transaction
+		err   error
+	}{
+		{"claim", 2, claim(preimage), nil},
+		{"claim at expiry", 3, claim(preimage), errLateClaim},
+		{"wrong preimage", 2, claim("00"), errInvalidPreimage},
+		{"refund at expiry", 3, refund(3), nil},
+		{"early refund", 2, refund(2), errEarlyRefund},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLedger()
+			genesis := Block{Transactions: []Transaction{genesisTransaction(alice.PublicKey, 101)}}
+			if err := l.ApplyBlock(genesis); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.ApplyBlock(Block{Index: 1, Transactions: []Transaction{lock}}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := l.ApplyBlock(Block{Index: tt.index, Transactions: []Transaction{tt.tx}})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ApplyBlock() = %v, want %v", err, tt.err)
+			}
+			if _, locked := l.Contract(lock.ID()); locked != (err != nil) {
+				t.Errorf("contract locked = %v after ApplyBlock() = %v", locked, err)
+			}
+		})
+	}
+}
+
+func TestLedgerUndoBlock(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	preimage := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	hashlock, _ := Hashlock(preimage)
+
+	l := NewLedger()
+	if err := l.ApplyBlock(Block{Transactions: []Transaction{genesisTransaction(alice.PublicKey, 1000)}}); err != nil {
+		t.Fatal(err)
+	}
+	genesisState := l.Clone()
+
+	lock := signed(alice, Transaction{
+		Sender: alice.PublicKey,
+		Fee:    1,
+		HTLC:   &HTLC{Recipient: bob.PublicKey, Hashlock: hashlock, Expiry: 10, Amount: 100},
+	})
+	block1 := Block{Index: 1, Transactions: []Transaction{lock, {Sender: MiningSender, Recipient: bob.PublicKey, Amount: 51, Nonce: 1}}}
+	if err := l.ApplyBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	block1State := l.Clone()
+
+	block2 := Block{Index: 2, Transactions: []Transaction{
+		signed(bob, Transaction{Sender: bob.PublicKey, Recipient: bob.PublicKey, Amount: 99, Fee: 1, Contract: lock.ID(), Preimage: preimage}),
+		signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Fee: 2, Nonce: 1}),
+		{Sender: MiningSender, Recipient: bob.PublicKey, Amount: 53, Nonce: 2},
+	}}
+	if err := l.ApplyBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+
+	l.UndoBlock(block2)
+	if !reflect.DeepEqual(l.Clone(), block1State) {
+		t.Errorf("undoing block 2 gives %+v, want %+v", l.Clone(), block1State)
+	}
+	l.UndoBlock(block1)
+	if !reflect.DeepEqual(l.Clone(), genesisState) {
+		t.Errorf("undoing block 1 gives %+v, want %+v", l.Clone(), genesisState)
+	}
+}
+
+func TestLedgerApplyBlockIsAtomic(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	l := NewLedger()
+	if err := l.ApplyBlock(Block{Transactions: []Transaction{genesisTransaction(alice.PublicKey, 100)}}); err != nil {
+		t.Fatal(err)
+	}
+	before := l.Clone()
+
+	pay := signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Fee: 1})
+	block := Block{Index: 1, Transactions: []Transaction{pay, pay}}
+	if err := l.ApplyBlock(block); !errors.Is(err, errInvalidNonce) {
+		t.Fatalf("ApplyBlock() = %v, want %v", err, errInvalidNonce)
+	}
+	if !reflect.DeepEqual(l.Clone(), before) {
+		t.Errorf("failed block changed the ledger to %+v, want %+v", l.Clone(), before)
+	}
+}
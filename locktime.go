@@ -0,0 +1,23 @@
+package main
+
+// LockTimeThreshold separates the two meanings of Transaction.LockTime,
+// lower values are block indexes and higher ones unix timestamps.
+const LockTimeThreshold = 500000000
+
+// IsFinal reports whether the transaction may be included in the block with index on top of a chain
+// with the median time past mtp. A transaction without lock time is always final, otherwise it is final
+// from the block with index LockTime on or once the median time past reached the LockTime timestamp.
+func (tx Transaction) IsFinal(index int64, mtp int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+	if tx.LockTime < LockTimeThreshold {
+		return index >= tx.LockTime
+	}
+	return mtp >= tx.LockTime
+}
+
+// IsFinalAfter reports whether the transaction may be included in the next block of the chain.
+func (tx Transaction) IsFinalAfter(chain []Block) bool {
+	return tx.IsFinal(int64(len(chain)), MedianTimePast(chain))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIsFinal(t *testing.T) {
+	const mtp = LockTimeThreshold + 1000
+
+	tests := []struct {
+		name     string
+		lockTime int64
+		index    int64
+		want     bool
+	}{
+		{"no lock time", 0, 1, true},
+		{"before the height", 10, 9, false},
+		{"at the height", 10, 10, true},
+		{"after the height", 10, 11, true},
+		{"before the time", mtp + 1, 1, false},
+		{"at the time", mtp, 1, true},
+		{"after the time", mtp - 1, 1, true},
+		// a timestamp is compared to the median time past, never to the height
+		{"time below the height", LockTimeThreshold, LockTimeThreshold + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := Transaction{LockTime: tt.lockTime}
+			if got := tx.IsFinal(tt.index, mtp); got != tt.want {
+				t.Errorf("IsFinal(%d, %d) = %v, want %v", tt.index, int64(mtp), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyLockTimes(t *testing.T) {
+	chain := chainWithTimestamps(LockTimeThreshold+10, LockTimeThreshold+20, LockTimeThreshold+30)
+
+	tests := []struct {
+		name     string
+		lockTime int64
+		err      error
+	}{
+		{"final at the height", 3, nil},
+		{"locked until a later height", 4, errNonFinalTransaction},
+		{"final at the median time past", LockTimeThreshold + 20, nil},
+		{"locked until a later time", LockTimeThreshold + 21, errNonFinalTransaction},
+		{"negative lock time", -1, errNonFinalTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := Block{Index: 3, Transactions: []Transaction{{Sender: "a", LockTime: tt.lockTime}}}
+			if err := Verification.VerifyLockTimes(chain, block); !errors.Is(err, tt.err) {
+				t.Errorf("VerifyLockTimes() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMiningWaitsForLockTime(t *testing.T) {
+	defer setupRegtest(t)()
+	alice, miner := newTestWallet(), newTestWallet()
+	GenesisBlock = Genesis{ChainID: "regtest", Allocations: map[string]Amount{alice.PublicKey: 100}}.Block()
+	b := newTestBlockChain(miner)
+
+	locked := signed(alice, Transaction{Sender: alice.PublicKey, Recipient: miner.PublicKey, Amount: 10, Fee: 1, LockTime: 3})
+	if !b.AddTransactionReceiving(locked) {
+		t.Fatal("the locked transaction wasn't kept open")
+	}
+	for index := 1; index <= 3; index++ {
+		block := b.MineBlock(context.Background())
+		if block == nil {
+			t.Fatal("mining failed")
+		}
+		if mined := containsTransaction(block.Transactions, locked); mined != (index == 3) {
+			t.Errorf("transaction locked until block 3 mined in block %d = %v", index, mined)
+		}
+	}
+	if len(b.OpenTransactions()) != 0 {
+		t.Error("the mined transaction is still open")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Miner keeps mining blocks of the open transactions in the background, while it's running and the node is in sync.
+type Miner struct {
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	started     time.Time
+	startHashes uint64
+	blocksFound int
+	template    *Block
+}
+
+// Start starts mining on the blockchain, it returns false if the miner is already running.
+func (m *Miner) Start(b *BlockChain) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.started = time.Now()
+	m.startHashes = atomic.LoadUint64(&hashesTried)
+	m.blocksFound = 0
+	go m.run(ctx, b)
+	return true
+}
+
+// Stop stops mining, it returns false if the miner isn't running.
+func (m *Miner) Stop() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel == nil {
+		return false
+	}
+	m.cancel()
+	m.cancel = nil
+	m.template = nil
+	return true
+}
+
+func (m *Miner) run(ctx context.Context, b *BlockChain) {
+	for ctx.Err() == nil {
+		if b.ResolveConflicts() {
+			m.setTemplate(nil)
+			m.wait(ctx, time.Second)
+			continue
+		}
+
+		template, ok := b.BlockTemplate(b.PublicKey())
+		if !ok {
+			m.setTemplate(nil)
+			m.wait(ctx, time.Second)
+			continue
+		}
+
+		m.setTemplate(&template)
+		if b.MineTemplate(ctx, template) == nil {
+			m.wait(ctx, time.Second)
+			continue
+		}
+
+		m.mu.Lock()
+		m.blocksFound++
+		m.mu.Unlock()
+		if _, pow := Consensus.(ProofOfWork); !pow || Params.NoRetargeting {
+			// no difficulty catches up with authorities or a fixed difficulty, don't flood the chain
+			m.wait(ctx, Params.TargetBlockTime)
+		}
+	}
+}
+
+func (m *Miner) wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (m *Miner) setTemplate(template *Block) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.template = template
+}
+
+// Status reports whether the miner is running and what it has done since it was started.
+func (m *Miner) Status(b *BlockChain) map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := map[string]interface{}{
+		"running":      m.cancel != nil,
+		"paused":       m.cancel != nil && b.ResolveConflicts(),
+		"blocks_found": m.blocksFound,
+		"hashrate":     0.0,
+		"template":     m.template,
+	}
+	if m.cancel != nil {
+		if elapsed := time.Since(m.started).Seconds(); elapsed > 0 {
+			status["hashrate"] = float64(atomic.LoadUint64(&hashesTried)-m.startHashes) / elapsed
+		}
+	}
+	return status
+}
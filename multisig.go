@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MultisigPrefix starts the addresses of multisignature accounts, they can't collide with public keys.
+const MultisigPrefix = "multisig:"
+
+// MaxMultisigKeys is the maximum number of public keys of a multisignature account.
+const MaxMultisigKeys = 16
+
+var (
+	errInvalidMultisig = errors.New("multisig needs a threshold between 1 and the number of distinct public keys")
+	errNotCosigner     = errors.New("wallet is not a key of the multisig of the transaction")
+)
+
+// Multisig defines a shared account whose transactions need signatures of Threshold of its PublicKeys.
+type Multisig struct {
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"public_keys"`
+}
+
+// Validate checks the threshold and that the public keys are distinct.
+func (m Multisig) Validate() error {
+	if m.Threshold < 1 || m.Threshold > len(m.PublicKeys) || len(m.PublicKeys) > MaxMultisigKeys {
+		return errInvalidMultisig
+	}
+	seen := map[string]bool{}
+	for _, key := range m.PublicKeys {
+		if key == "" || seen[key] {
+			return errInvalidMultisig
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Address returns the account of the multisig, it doesn't depend on the order of the public keys.
+func (m Multisig) Address() string {
+	keys := append([]string(nil), m.PublicKeys...)
+	sort.Strings(keys)
+	return MultisigPrefix + HashString256(fmt.Sprintf("%d", m.Threshold)+strings.Join(keys, ","))
+}
+
+// HasKey reports whether the public key is one of the keys of the multisig.
+func (m Multisig) HasKey(publicKey string) bool {
+	for _, key := range m.PublicKeys {
+		if key == publicKey {
+			return true
+		}
+	}
+	return false
+}
+
+// CosignTransaction adds the signature of the wallet to a transaction of a multisig the wallet is a key of.
+func (w *Wallet) CosignTransaction(transaction *Transaction) error {
+	if transaction.Multisig == nil || !transaction.Multisig.HasKey(w.PublicKey) {
+		return errNotCosigner
+	}
+	if transaction.Signatures == nil {
+		transaction.Signatures = map[string]string{}
+	}
+	transaction.Signatures[w.PublicKey] = w.SignTransaction(*transaction)
+	return nil
+}
+
+// VerifyMultisig reports whether the transaction is sent by the address of its multisig
+// and carries valid signatures of at least the threshold of its keys.
+func (w Wallet) VerifyMultisig(transaction Transaction) bool {
+	m := transaction.Multisig
+	if m == nil || m.Validate() != nil || transaction.Sender != m.Address() || transaction.Signature != "" {
+		return false
+	}
+
+	payload := transaction.SigningPayload()
+	valid := 0
+	for publicKey, signature := range transaction.Signatures {
+		if !m.HasKey(publicKey) || !w.VerifySignature(publicKey, payload, signature) {
+			return false
+		}
+		valid++
+	}
+	return valid >= m.Threshold
+}
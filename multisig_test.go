@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMultisigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Multisig
+		err  error
+	}{
+		{"1 of 1", Multisig{Threshold: 1, PublicKeys: []string{"a"}}, nil},
+		{"2 of 3", Multisig{Threshold: 2, PublicKeys: []string{"a", "b", "c"}}, nil},
+		{"zero threshold", Multisig{Threshold: 0, PublicKeys: []string{"a"}}, errInvalidMultisig},
+		{"threshold above the keys", Multisig{Threshold: 3, PublicKeys: []string{"a", "b"}}, errInvalidMultisig},
+		{"duplicate key", Multisig{Threshold: 2, PublicKeys: []string{"a", "a"}}, errInvalidMultisig},
+		{"empty key", Multisig{Threshold: 1, PublicKeys: []string{""}}, errInvalidMultisig},
+		{"too many keys", Multisig{Threshold: 1, PublicKeys: make([]string, MaxMultisigKeys+1)}, errInvalidMultisig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.Validate(); !errors.Is(err, tt.err) {
+				t.Errorf("Validate() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMultisigAddress(t *testing.T) {
+	a := Multisig{Threshold: 2, PublicKeys: []string{"a", "b", "c"}}
+	if a.Address() != (Multisig{Threshold: 2, PublicKeys: []string{"c", "a", "b"}}).Address() {
+		t.Error("the address depends on the order of the keys")
+	}
+	if a.Address() == (Multisig{Threshold: 1, PublicKeys: []string{"a", "b", "c"}}).Address() {
+		t.Error("multisigs with different thresholds share the address")
+	}
+}
+
+func TestVerifyMultisig(t *testing.T) {
+	keys := []Wallet{newTestWallet(), newTestWallet(), newTestWallet()}
+	outsider := newTestWallet()
+	m := &Multisig{Threshold: 2, PublicKeys: []string{keys[0].PublicKey, keys[1].PublicKey, keys[2].PublicKey}}
+	// cosigned copies the signatures so that the transactions of the table don't share them
+	cosigned := func(tx Transaction, signers ...Wallet) Transaction {
+		for _, w := range signers {
+			signatures := map[string]string{}
+			for k, v := range tx.Signatures {
+				signatures[k] = v
+			}
+			tx.Signatures = signatures
+			tx.Signatures[w.PublicKey] = w.SignTransaction(tx)
+		}
+		return tx
+	}
+	tx := Transaction{Sender: m.Address(), Recipient: outsider.PublicKey, Amount: 10, Fee: 1, Multisig: m}
+	tampered := cosigned(tx, keys[0], keys[1])
+	tampered.Amount = 100
+	otherSender := tx
+	otherSender.Sender = keys[0].PublicKey
+	withSignature := cosigned(tx, keys[0], keys[1])
+	withSignature.Signature = keys[2].SignTransaction(tx)
+
+	tests := []struct {
+		name string
+		tx   Transaction
+		want bool
+	}{
+		{"no signatures", tx, false},
+		{"below the threshold", cosigned(tx, keys[0]), false},
+		{"at the threshold", cosigned(tx, keys[0], keys[2]), true},
+		{"all keys", cosigned(tx, keys[0], keys[1], keys[2]), true},
+		{"signature of an outsider", cosigned(tx, keys[0], outsider), false},
+		{"tampered after signing", tampered, false},
+		{"sender isn't the multisig address", cosigned(otherSender, keys[0], keys[1]), false},
+		{"single signature as well", withSignature, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Wallet{}).VerifyMultisig(tt.tx); got != tt.want {
+				t.Errorf("VerifyMultisig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	l := NewLedger()
+	if err := l.ApplyBlock(Block{Transactions: []Transaction{genesisTransaction(m.Address(), 100)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyTransaction(cosigned(tx, keys[1])); !errors.Is(err, errInvalidSignature) {
+		t.Errorf("ApplyTransaction() = %v below the threshold, want %v", err, errInvalidSignature)
+	}
+	if err := l.ApplyTransaction(cosigned(tx, keys[1], keys[2])); err != nil {
+		t.Errorf("ApplyTransaction() = %v at the threshold", err)
+	}
+	if l.Balance(m.Address()) != 89 {
+		t.Errorf("balance of the multisig = %d, want 89", l.Balance(m.Address()))
+	}
+}
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 var (
 	wallet     Wallet
 	blockchain BlockChain
+	miner      Miner
+	pool       *Pool
 )
 
 func createKeys(w http.ResponseWriter, r *http.Request) {
@@ -30,7 +36,7 @@ func createKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blockchain.PublicKey = wallet.PublicKey
+	blockchain.SetPublicKey(wallet.PublicKey)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -56,7 +62,7 @@ func loadKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blockchain.PublicKey = wallet.PublicKey
+	blockchain.SetPublicKey(wallet.PublicKey)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -84,6 +90,67 @@ func getBalance(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Fetched balance successfully.",
 		"funds":   balance,
+		"nonce":   blockchain.NextNonce(wallet.PublicKey),
+	})
+}
+
+func getUnspentOutputs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	account := r.URL.Query().Get("account")
+	if account == "" {
+		account = wallet.PublicKey
+	}
+	utxos, ok := blockchain.Ledger().(*UTXOSet)
+	if !ok || account == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Node doesn't run the UTXO ledger or no account given.",
+		})
+		return
+	}
+
+	unspent := make([]map[string]interface{}, 0)
+	for _, op := range utxos.Unspent(account) {
+		out, _ := utxos.Output(op)
+		unspent = append(unspent, map[string]interface{}{
+			"tx_id":  op.TxID,
+			"index":  op.Index,
+			"amount": out.Amount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"account": account,
+		"unspent": unspent,
+		"funds":   utxos.Balance(account),
+	})
+}
+
+func getSupply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	issued := blockchain.IssuedSupply()
+	nextIndex := blockchain.GetLastBlock().Index + 1
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issued":       issued,
+		"remaining":    Params.Policy.MaxSupply - issued,
+		"max_supply":   Params.Policy.MaxSupply,
+		"block_reward": Params.Policy.Reward(nextIndex),
+		"next_halving": Params.Policy.NextHalving(nextIndex),
+		"decimals":     Decimals,
 	})
 }
 
@@ -93,39 +160,662 @@ func getNodeUI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.ServeFile(w, r, "ui/node.html")
+	http.ServeFile(w, r, "ui/node.html")
+}
+
+func getNetworkUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	http.ServeFile(w, r, "ui/network.html")
+}
+
+func broadcastTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var tx Transaction
+	err := json.NewDecoder(r.Body).Decode(&tx)
+	cost, costErr := tx.Cost()
+	if err != nil || costErr != nil || tx.Sender == "" || (tx.Recipient == "" && len(tx.Outputs) == 0 && tx.HTLC == nil) || (cost == 0 && tx.Vote == "") || (tx.Signature == "" && len(tx.Signatures) == 0) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Some tx is missing.",
+		})
+		return
+	}
+
+	if !blockchain.AddTransactionReceiving(tx) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Creating a transaction failed.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Successfully added transaction.",
+		"transaction": tx,
+		"id":          tx.ID(),
+	})
+}
+
+func broadcastBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data struct {
+		Block     *Block `json:"block"`
+		ChainWork string `json:"chain_work"`
+		Peer      string `json:"peer"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Block == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Some tx is missing.",
+		})
+		return
+	}
+	block := *data.Block
+	lastBlock := blockchain.GetLastBlock()
+	if block.Index > lastBlock.Index && blockchain.BlockByHash(block.PreviousHash) == nil {
+		if err := blockchain.AddOrphan(block, data.Peer); err != nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": fmt.Sprintf("Orphan block rejected: %v.", err),
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Parent block unknown, block stored as orphan.",
+		})
+		return
+	}
+	if block.PreviousHash != lastBlock.Hash() || block.Index != lastBlock.Index+1 {
+		remoteWork, ok := new(big.Int).SetString(data.ChainWork, 10)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Chain work is missing.",
+			})
+			return
+		}
+
+		if !HeavierChain(remoteWork, block.Hash(), ChainWork(blockchain.Chain()), lastBlock.Hash()) {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Blockchain seems to have less work, block not added.",
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Blockchain seems to differ from local blockchain.",
+		})
+
+		blockchain.SetResolveConflicts()
+
+		return
+	}
+	//if block.Index == blockchain.GetLastBlock().Index+1
+	if !Verification.ValidTimestamp(blockchain.Chain(), block.Timestamp) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Block timestamp is invalid.",
+		})
+		return
+	}
+	if !blockchain.AddBlock(block) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Block seems invalid.",
+		})
+		return
+	}
+	blockchain.ConnectOrphans()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Block added.",
+	})
+}
+
+func getBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	block := blockchain.BlockByHash(strings.TrimPrefix(r.URL.Path, "/block/"))
+	if block == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Block not found.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(block)
+}
+
+func getTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	tx, block, ok := blockchain.TransactionByID(strings.TrimPrefix(r.URL.Path, "/tx/"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Transaction not found.",
+		})
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":            tx.ID(),
+		"transaction":   tx,
+		"status":        "pending",
+		"confirmations": 0,
+		"final":         tx.IsFinalAfter(blockchain.Chain()),
+	}
+	if block != nil {
+		data["status"] = "confirmed"
+		data["final"] = true
+		data["block_hash"] = block.Hash()
+		data["block_index"] = block.Index
+		data["confirmations"] = blockchain.GetLastBlock().Index - block.Index + 1
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// payment is the request body paying either a single recipient and amount or a list of payees by one transaction.
+type payment struct {
+	Recipient string   `json:"recipient"`
+	Amount    Amount   `json:"amount"`
+	Fee       Amount   `json:"fee"`
+	Payees    []Output `json:"payees"`
+	LockTime  int64    `json:"lock_time"`
+}
+
+func (p payment) Validate() error {
+	payees := p.Payees
+	if len(payees) == 0 {
+		payees = []Output{{Recipient: p.Recipient, Amount: p.Amount}}
+	} else if p.Recipient != "" || p.Amount != 0 {
+		return errInvalidOutput
+	}
+	var total Amount
+	for _, payee := range payees {
+		var ok bool
+		if total, ok = addAmount(total, payee.Amount); !ok || payee.Recipient == "" || payee.Amount == 0 {
+			return errInvalidOutput
+		}
+	}
+	if p.Fee < 0 || p.LockTime < 0 {
+		return errInvalidAmount
+	}
+	return nil
+}
+
+// Transaction returns the unfunded and unsigned transaction of the payment.
+func (p payment) Transaction(sender string) Transaction {
+	return Transaction{
+		Sender:    sender,
+		Recipient: p.Recipient,
+		Amount:    p.Amount,
+		Fee:       p.Fee,
+		Outputs:   p.Payees,
+		LockTime:  p.LockTime,
+	}
+}
+
+func addTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if wallet.PublicKey == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "No wallet set up.",
+		})
+		return
+	}
+
+	var data payment
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Validate() != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
+		})
+		return
+	}
+	tx := data.Transaction(wallet.PublicKey)
+	err := blockchain.FundTransaction(&tx)
+	tx.Signature = wallet.SignTransaction(tx)
+
+	if err != nil || !blockchain.AddTransaction(tx) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Creating a transaction failed.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Successfully added transaction.",
+		"transaction": tx,
+		"id":          tx.ID(),
+		"funds":       blockchain.GetBalance(),
+	})
+}
+
+func createMultisig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var m Multisig
+	if json.NewDecoder(r.Body).Decode(&m) != nil || m.Validate() != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Threshold or public keys are invalid.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"address":  m.Address(),
+		"multisig": m,
+		"funds":    blockchain.GetBalanceWithSender(m.Address()),
+	})
+}
+
+// writeMultisigTransaction responds with the partially signed transaction and how many signatures it still needs.
+func writeMultisigTransaction(w http.ResponseWriter, status int, message string, tx Transaction) {
+	missing := tx.Multisig.Threshold - len(tx.Signatures)
+	if missing < 0 {
+		missing = 0
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            message,
+		"transaction":        tx,
+		"missing_signatures": missing,
+	})
+}
+
+func proposeMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data struct {
+		payment
+		Multisig Multisig `json:"multisig"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Validate() != nil || data.Multisig.Validate() != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
+		})
+		return
+	}
+	tx := data.Transaction(data.Multisig.Address())
+	tx.Multisig = &data.Multisig
+	if err := blockchain.FundTransaction(&tx); err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Multisig has insufficient funds.",
+		})
+		return
+	}
+	if wallet.PublicKey != "" && tx.Multisig.HasKey(wallet.PublicKey) {
+		_ = wallet.CosignTransaction(&tx)
+	}
+
+	writeMultisigTransaction(w, http.StatusCreated, "Proposed transaction, pass it on to the co-signers.", tx)
+}
+
+func signMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var tx Transaction
+	if json.NewDecoder(r.Body).Decode(&tx) != nil || tx.Multisig == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Multisig transaction is missing.",
+		})
+		return
+	}
+	if err := wallet.CosignTransaction(&tx); err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Wallet is not a key of the multisig.",
+		})
+		return
+	}
+
+	writeMultisigTransaction(w, http.StatusOK, "Signed transaction.", tx)
+}
+
+func submitMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var tx Transaction
+	if json.NewDecoder(r.Body).Decode(&tx) != nil || tx.Multisig == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Multisig transaction is missing.",
+		})
+		return
+	}
+	if !(Wallet{}).VerifyMultisig(tx) {
+		writeMultisigTransaction(w, http.StatusBadRequest, "Transaction lacks valid signatures.", tx)
+		return
+	}
+	if !blockchain.AddTransaction(tx) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Creating a transaction failed.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Successfully added transaction.",
+		"transaction": tx,
+		"id":          tx.ID(),
+	})
+}
+
+func createHTLC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if wallet.PublicKey == "" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "No wallet set up.",
+		})
+		return
+	}
+
+	var data struct {
+		HTLC
+		Fee Amount `json:"fee"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&data)
+	// Without a hashlock the node creates the secret, it's revealed only to the caller.
+	var preimage string
+	if err == nil && data.Hashlock == "" {
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		preimage = hex.EncodeToString(secret)
+		data.Hashlock, _ = Hashlock(preimage)
+	}
+	if err != nil || data.HTLC.Validate() != nil || data.Fee < 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
+		})
+		return
+	}
+	tx := Transaction{
+		Sender: wallet.PublicKey,
+		Fee:    data.Fee,
+		HTLC:   &data.HTLC,
+	}
+	err = blockchain.FundTransaction(&tx)
+	tx.Signature = wallet.SignTransaction(tx)
+
+	if err != nil || !blockchain.AddTransaction(tx) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Creating a contract failed.",
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":     "Successfully locked contract.",
+		"transaction": tx,
+		"contract":    tx.ID(),
+		"hashlock":    data.Hashlock,
+		"funds":       blockchain.GetBalance(),
+	}
+	if preimage != "" {
+		response["preimage"] = preimage
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// redeemHTLC claims the contract with the preimage or refunds it without, the whole amount minus the fee
+// is paid to the wallet. A refund is locked until the expiry of the contract and stays open until then.
+func redeemHTLC(w http.ResponseWriter, r *http.Request, claim bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data struct {
+		Contract string `json:"contract"`
+		Preimage string `json:"preimage"`
+		Fee      Amount `json:"fee"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || wallet.PublicKey == "" || data.Contract == "" || (data.Preimage != "") != claim || data.Fee < 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
+		})
+		return
+	}
+	c, ok := blockchain.Ledger().Contract(data.Contract)
+	if !ok || c.Amount < data.Fee {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Contract not found or already redeemed.",
+		})
+		return
+	}
+	tx := Transaction{
+		Sender:    wallet.PublicKey,
+		Recipient: wallet.PublicKey,
+		Amount:    c.Amount - data.Fee,
+		Fee:       data.Fee,
+		Contract:  data.Contract,
+		Preimage:  data.Preimage,
+	}
+	if !claim {
+		tx.LockTime = c.Expiry
+	}
+	err := blockchain.FundTransaction(&tx)
+	tx.Signature = wallet.SignTransaction(tx)
+
+	if err != nil || !blockchain.AddTransaction(tx) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Redeeming the contract failed.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Successfully added transaction.",
+		"transaction": tx,
+		"id":          tx.ID(),
+	})
+}
+
+func claimHTLC(w http.ResponseWriter, r *http.Request) {
+	redeemHTLC(w, r, true)
+}
+
+func refundHTLC(w http.ResponseWriter, r *http.Request) {
+	redeemHTLC(w, r, false)
+}
+
+func getHTLC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	c, ok := blockchain.Ledger().Contract(strings.TrimPrefix(r.URL.Path, "/htlc/"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Contract not found or already redeemed.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"contract": c,
+		"expired":  blockchain.GetLastBlock().Index+1 >= c.Expiry,
+	})
 }
 
-func getNetworkUI(w http.ResponseWriter, r *http.Request) {
+func getPreimage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
 
-	http.ServeFile(w, r, "ui/network.html")
+	tx, block, ok := blockchain.RevealedPreimage(strings.TrimPrefix(r.URL.Path, "/htlc/preimage/"))
+	if !ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Preimage not revealed on chain.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"preimage":    tx.Preimage,
+		"contract":    tx.Contract,
+		"id":          tx.ID(),
+		"block_hash":  block.Hash(),
+		"block_index": block.Index,
+	})
 }
 
-func broadcastTransaction(w http.ResponseWriter, r *http.Request) {
+func addVote(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
 
-	var tx Transaction
-	if json.NewDecoder(r.Body).Decode(&tx) != nil || tx.Sender == "" || tx.Recipient == "" || tx.Amount == 0 || tx.Signature == "" {
+	if wallet.PublicKey == "" {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Some tx is missing.",
+			"message": "No wallet set up.",
+		})
+		return
+	}
+
+	var data struct {
+		Candidate string `json:"candidate"`
+		Vote      string `json:"vote"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Candidate == "" || (data.Vote != VoteAdd && data.Vote != VoteRemove) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
 		})
 		return
 	}
+	tx := Transaction{
+		Sender:    wallet.PublicKey,
+		Recipient: data.Candidate,
+		Vote:      data.Vote,
+	}
+	err := blockchain.FundTransaction(&tx)
+	tx.Signature = wallet.SignTransaction(tx)
 
-	if !blockchain.AddTransactionReceiving(tx.Recipient, tx.Sender, tx.Signature, tx.Amount) {
+	if err != nil || !blockchain.AddTransaction(tx) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Creating a transaction failed.",
+			"message": "Creating a vote failed.",
 		})
 		return
 	}
@@ -133,66 +823,121 @@ func broadcastTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":     "Successfully added transaction.",
+		"message":     "Successfully added vote.",
 		"transaction": tx,
+		"id":          tx.ID(),
 	})
 }
 
-func broadcastBlock(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+func getSigners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
 
-	var data struct {
-		Block *Block `json:"block"`
-	}
-	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Block == nil {
+	poa, ok := Consensus.(ProofOfAuthority)
+	if !ok {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusNotFound)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Some tx is missing.",
+			"message": "Node is not running proof of authority.",
 		})
 		return
 	}
-	block := *data.Block
-	if block.Index < blockchain.GetLastBlock().Index+1 {
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"signers": poa.SignersAt(blockchain.Chain()),
+	})
+}
+
+func mine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if blockchain.ResolveConflicts() {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusConflict)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Blockchain seems to be shorter, block not added.",
+			"message": "Resolve conflicts first, block not added!",
 		})
 		return
 	}
 
-	if block.Index > blockchain.GetLastBlock().Index+1 {
+	block := blockchain.MineBlock(r.Context())
+	if block == nil {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusConflict)
+		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Blockchain seems to differ from local blockchain.",
+			"message":       "Adding a block failed.",
+			"wallet_set_up": wallet.PublicKey != "",
 		})
+		return
+	}
 
-		blockchain.ResolveConflicts = true
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Block added successfully.",
+		"block":   block,
+		"funds":   blockchain.GetBalance(),
+	})
+}
 
+func generate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
-	//if block.Index == blockchain.GetLastBlock().Index+1
-	if !blockchain.AddBlock(block) {
+
+	if !Params.AllowGenerate {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusConflict)
+		w.WriteHeader(http.StatusForbidden)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Block seems invalid.",
+			"message": fmt.Sprintf("Generating blocks isn't allowed on %s.", Params.Name),
+		})
+		return
+	}
+
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n < 1 || n > 1000 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Number of blocks n has to be between 1 and 1000.",
 		})
 		return
 	}
+
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		block := blockchain.MineBlock(r.Context())
+		if block == nil {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"message":       "Adding a block failed.",
+				"wallet_set_up": wallet.PublicKey != "",
+				"blocks":        hashes,
+			})
+			return
+		}
+		hashes = append(hashes, block.Hash())
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Block added.",
+		"message": fmt.Sprintf("Generated %d blocks.", n),
+		"blocks":  hashes,
+		"funds":   blockchain.GetBalance(),
 	})
 }
 
-func addTransaction(w http.ResponseWriter, r *http.Request) {
+func startMiner(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
@@ -207,75 +952,232 @@ func addTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var data struct {
-		Recipient string  `json:"recipient"`
-		Amount    float64 `json:"amount"`
+	if !miner.Start(&blockchain) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Miner is already running.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Miner started.",
+	})
+}
+
+func stopMiner(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
 	}
-	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Recipient == "" || data.Amount == 0 {
+
+	if !miner.Stop() {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusConflict)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Required data is missing.",
+			"message": "Miner isn't running.",
 		})
 		return
 	}
-	signature := wallet.SignTransaction(wallet.PublicKey, data.Recipient, data.Amount)
 
-	if !blockchain.AddTransaction(data.Recipient, wallet.PublicKey, signature, data.Amount) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Miner stopped.",
+	})
+}
+
+func getMinerStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(miner.Status(&blockchain))
+}
+
+// getMiningTemplate returns a block template for external miners.
+// A proof is valid if the SHA-256 of proof_prefix followed by the decimal proof is not above the target.
+// The proof covers the whole block, it has to be submitted unchanged but for the proof.
+func getMiningTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	payTo := r.URL.Query().Get("address")
+	if payTo == "" {
+		payTo = wallet.PublicKey
+	}
+
+	block, ok := blockchain.BlockTemplate(payTo)
+	if !ok {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Creating a transaction failed.",
+			"message":       "Creating a block template failed.",
+			"wallet_set_up": wallet.PublicKey != "",
 		})
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Successfully added transaction.",
-		"transaction": Transaction{
-			Sender:    wallet.PublicKey,
-			Recipient: data.Recipient,
-			Amount:    data.Amount,
-			Signature: signature,
-		},
-		"funds": blockchain.GetBalance(),
+		"block":         block,
+		"previous_hash": block.PreviousHash,
+		"transactions":  block.Transactions,
+		"reward":        block.Transactions[len(block.Transactions)-1].Amount,
+		"difficulty":    block.Difficulty,
+		"target":        DifficultyTarget(block.Difficulty),
+		"proof_prefix":  proofPrefix(block),
 	})
 }
 
-func mine(w http.ResponseWriter, r *http.Request) {
+func submitBlock(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		return
 	}
 
-	if blockchain.ResolveConflicts {
+	var data struct {
+		Block *Block `json:"block"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Block == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Block is missing.",
+		})
+		return
+	}
+
+	if !blockchain.SubmitBlock(*data.Block) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusConflict)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Resolve conflicts first, block not added!",
+			"message": "Block seems invalid or stale.",
 		})
 		return
 	}
 
-	block := blockchain.MineBlock()
-	if block == nil {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Block added successfully.",
+		"block":   data.Block,
+	})
+}
+
+func getPoolWork(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if pool == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Node isn't running a mining pool.",
+		})
+		return
+	}
+
+	id, block, ok := pool.Work(&blockchain)
+	if !ok {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.WriteHeader(http.StatusInternalServerError)
 		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"message":       "Adding a block failed.",
+			"message":       "Creating a block template failed.",
 			"wallet_set_up": wallet.PublicKey != "",
 		})
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"template_id":  id,
+		"coinbase":     block.Transactions[len(block.Transactions)-1],
+		"proof_prefix": proofPrefix(block),
+		"target":       DifficultyTarget(block.Difficulty),
+		"share_target": DifficultyTarget(ShareDifficulty(block.Difficulty)),
+	})
+}
+
+func submitPoolShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if pool == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Node isn't running a mining pool.",
+		})
+		return
+	}
+
+	var data struct {
+		Miner      string  `json:"miner"`
+		TemplateID string  `json:"template_id"`
+		Proof      *uint64 `json:"proof"`
+	}
+	if json.NewDecoder(r.Body).Decode(&data) != nil || data.Miner == "" || data.TemplateID == "" || data.Proof == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Required data is missing.",
+		})
+		return
+	}
+
+	found, err := pool.SubmitShare(&blockchain, data.Miner, data.TemplateID, *data.Proof)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": fmt.Sprintf("Share rejected, %v.", err),
+		})
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Block added successfully.",
-		"block":   block,
-		"funds":   blockchain.GetBalance(),
+		"message":     "Share accepted.",
+		"block_found": found,
+	})
+}
+
+func getPoolStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if pool == nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Node isn't running a mining pool.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"window": PoolWindow,
+		"work":   pool.Stats(),
 	})
 }
 
@@ -344,7 +1246,14 @@ func addNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blockchain.AddPeerNode(data.Node)
+	if !blockchain.AddPeerNode(data.Node) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Node runs a different genesis block.",
+		})
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -380,6 +1289,21 @@ func removeNode(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func getGenesis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"hash":     GenesisBlock.Hash(),
+		"chain_id": GenesisBlock.ChainID,
+		"block":    GenesisBlock,
+	})
+}
+
 func getNode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
@@ -395,18 +1319,77 @@ func getNode(w http.ResponseWriter, r *http.Request) {
 
 func main() {
 	var port int
-	flag.IntVar(&port, "port", 5000, "")
-	flag.IntVar(&port, "p", 5000, "")
+	flag.IntVar(&port, "port", 0, "defaults to the port of the network")
+	flag.IntVar(&port, "p", 0, "defaults to the port of the network")
+	var network, host, consensus, signers, genesis string
+	flag.StringVar(&network, "network", MainnetParams.Name, "network to run on, mainnet, testnet or regtest")
+	flag.StringVar(&genesis, "genesis", "", "JSON file describing the genesis block, defaults to the genesis of the network")
+	flag.StringVar(&host, "host", "localhost", "host name under which peers reach this node")
+	flag.StringVar(&consensus, "consensus", "pow", "consensus engine, pow or poa")
+	flag.StringVar(&signers, "signers", "signers.json", "JSON list of the initial proof of authority signers")
+	var ledger string
+	flag.StringVar(&ledger, "ledger", "account", "ledger model, account balances or utxo, all nodes of a network have to agree")
+	var runPool bool
+	flag.BoolVar(&runPool, "pool", false, "run a mining pool for external miners")
 	flag.Parse()
 
+	params, ok := Networks[network]
+	if !ok {
+		log.Fatalf("unknown network %q", network)
+	}
+	Params = params
+	if port == 0 {
+		port = Params.DefaultPort
+	}
+
 	wallet.NodeID = port
-	blockchain = BlockChain{PublicKey: wallet.PublicKey, NodeID: port}
+	g := LoadGenesis(genesis)
+	supply, ok := g.Supply()
+	if !ok {
+		log.Fatalf("genesis allocates a negative amount or more than the max supply of %s", Params.Policy.MaxSupply)
+	}
+	Params.Policy.Premine = supply
+	GenesisBlock = g.Block()
+	switch consensus {
+	case "pow":
+		Consensus = ProofOfWork{}
+	case "poa":
+		Consensus = ProofOfAuthority{Wallet: &wallet, Signers: LoadSigners(signers)}
+	default:
+		log.Fatalf("unknown consensus engine %q", consensus)
+	}
+	switch ledger {
+	case "account":
+		NewLedgerState = func() LedgerState { return NewLedger() }
+	case "utxo":
+		NewLedgerState = func() LedgerState { return NewUTXOSet() }
+	default:
+		log.Fatalf("unknown ledger model %q", ledger)
+	}
+	if runPool {
+		if _, ok := Consensus.(ProofOfWork); !ok {
+			log.Fatal("mining pool needs proof of work consensus")
+		}
+		pool = &Pool{}
+	}
+	blockchain = BlockChain{publicKey: wallet.PublicKey, NodeID: port, Address: fmt.Sprintf("%s:%d", host, port)}
 	blockchain.LoadData()
 
 	http.HandleFunc("/", getNodeUI)
 	http.HandleFunc("/network", getNetworkUI)
 	http.HandleFunc("/mine", mine)
+	http.HandleFunc("/generate", generate)
+	http.HandleFunc("/miner/start", startMiner)
+	http.HandleFunc("/miner/stop", stopMiner)
+	http.HandleFunc("/miner/status", getMinerStatus)
+	http.HandleFunc("/mining/template", getMiningTemplate)
+	http.HandleFunc("/mining/submit", submitBlock)
+	http.HandleFunc("/pool/work", getPoolWork)
+	http.HandleFunc("/pool/share", submitPoolShare)
+	http.HandleFunc("/pool/stats", getPoolStats)
 	http.HandleFunc("/chain", getChain)
+	http.HandleFunc("/genesis", getGenesis)
+	http.HandleFunc("/block/", getBlock)
 	http.HandleFunc("/wallet", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
@@ -420,13 +1403,27 @@ func main() {
 	})
 	http.HandleFunc("/transaction", addTransaction)
 	http.HandleFunc("/transactions", getTransactions)
+	http.HandleFunc("/tx/", getTransaction)
 	http.HandleFunc("/balance", getBalance)
+	http.HandleFunc("/utxos", getUnspentOutputs)
+	http.HandleFunc("/supply", getSupply)
 	http.HandleFunc("/nodes", getNode)
 	http.HandleFunc("/node", addNode)
 	http.HandleFunc("/node/", removeNode)
 	http.HandleFunc("/broadcast-transaction", broadcastTransaction)
 	http.HandleFunc("/broadcast-block", broadcastBlock)
 	http.HandleFunc("/resolve-conflicts", resolveConflicts)
+	http.HandleFunc("/vote", addVote)
+	http.HandleFunc("/multisig", createMultisig)
+	http.HandleFunc("/multisig/propose", proposeMultisigTransaction)
+	http.HandleFunc("/multisig/sign", signMultisigTransaction)
+	http.HandleFunc("/multisig/submit", submitMultisigTransaction)
+	http.HandleFunc("/htlc", createHTLC)
+	http.HandleFunc("/htlc/claim", claimHTLC)
+	http.HandleFunc("/htlc/refund", refundHTLC)
+	http.HandleFunc("/htlc/preimage/", getPreimage)
+	http.HandleFunc("/htlc/", getHTLC)
+	http.HandleFunc("/signers", getSigners)
 
 	addr := fmt.Sprintf("0.0.0.0:%d", port)
 	fmt.Printf("* Running on http://%s/ (Press CTRL+C to quit)\n", addr)
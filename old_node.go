@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -24,14 +25,20 @@ func (n *Node) ListenForInput() {
 		switch n.GetUserChoice() {
 		case "1":
 			txRecipient, txAmount := n.GetTransactionValue()
-			signature := n.Wallet.SignTransaction(n.Wallet.PublicKey, txRecipient, txAmount)
-			if !n.BlockChain.AddTransaction(txRecipient, n.Wallet.PublicKey, signature, txAmount) {
+			tx := Transaction{
+				Sender:    n.Wallet.PublicKey,
+				Recipient: txRecipient,
+				Amount:    txAmount,
+			}
+			err := n.BlockChain.FundTransaction(&tx)
+			tx.Signature = n.Wallet.SignTransaction(tx)
+			if err != nil || !n.BlockChain.AddTransaction(tx) {
 				fmt.Println("Transaction failed!")
 				break
 			}
 			fmt.Println("Added Transaction!")
 		case "2":
-			if n.BlockChain.MineBlock() == nil {
+			if n.BlockChain.MineBlock(context.Background()) == nil {
 				fmt.Println("Mining failed. Got no wallet?")
 			}
 		case "3":
@@ -44,11 +51,11 @@ func (n *Node) ListenForInput() {
 			fmt.Println("All Transactions are valid")
 		case "5":
 			n.Wallet.CreateKeys()
-			n.BlockChain.PublicKey = n.Wallet.PublicKey
+			n.BlockChain.SetPublicKey(n.Wallet.PublicKey)
 			n.BlockChain.LoadData()
 		case "6":
 			n.Wallet.LoadKeys()
-			n.BlockChain.PublicKey = n.Wallet.PublicKey
+			n.BlockChain.SetPublicKey(n.Wallet.PublicKey)
 			n.BlockChain.LoadData()
 		case "7":
 			n.Wallet.SaveKeys()
@@ -57,15 +64,15 @@ func (n *Node) ListenForInput() {
 		default:
 			fmt.Println("Input was invalid, please pick a value from the list!")
 		}
-		fmt.Printf("Balance of %s: %6.2f\n", n.Wallet, n.BlockChain.GetBalance())
+		fmt.Printf("Balance of %s: %s\n", n.Wallet.PublicKey, n.BlockChain.GetBalance())
 
-		if !Verification.VerifyChain(n.BlockChain.Chain()) {
-			panic("chain is broken")
+		if err := Verification.VerifyChain(n.BlockChain.Chain()); err != nil {
+			panic(err)
 		}
 	}
 }
 
-func (n Node) GetTransactionValue() (string, float64) {
+func (n *Node) GetTransactionValue() (string, Amount) {
 	fmt.Print("Enter the recipient of the Transaction: ")
 	var s string
 	if _, err := fmt.Scanf("%s", &s); err != nil {
@@ -73,14 +80,18 @@ func (n Node) GetTransactionValue() (string, float64) {
 	}
 
 	fmt.Print("Your Transaction amount please: ")
-	var f float64
-	if _, err := fmt.Scanf("%f", &f); err != nil {
+	var f string
+	if _, err := fmt.Scanf("%s", &f); err != nil {
+		panic(err)
+	}
+	amount, err := ParseAmount(f)
+	if err != nil {
 		panic(err)
 	}
-	return s, f
+	return s, amount
 }
 
-func (n Node) GetUserChoice() string {
+func (n *Node) GetUserChoice() string {
 	var s string
 	if _, err := fmt.Scan(&s); err != nil {
 		panic(err)
@@ -88,7 +99,7 @@ func (n Node) GetUserChoice() string {
 	return s
 }
 
-func (n Node) PrintBlockChainElements() {
+func (n *Node) PrintBlockChainElements() {
 	for _, block := range n.BlockChain.Chain() {
 		fmt.Println(block)
 	}
@@ -97,7 +108,7 @@ func (n Node) PrintBlockChainElements() {
 func _main() {
 	var n Node
 	n.Wallet.CreateKeys()
-	n.BlockChain = BlockChain{PublicKey: n.Wallet.PublicKey}
+	n.BlockChain = BlockChain{publicKey: n.Wallet.PublicKey}
 	n.BlockChain.LoadData()
 	n.ListenForInput()
 }
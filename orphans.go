@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// MaxOrphanBlocks bounds the number of blocks waiting for their parent.
+	MaxOrphanBlocks = 100
+	// OrphanExpiry is how long an orphan block is kept before its parent has to arrive.
+	OrphanExpiry = 10 * time.Minute
+)
+
+type orphanBlock struct {
+	block    Block
+	peer     string
+	received time.Time
+}
+
+// OrphanPool holds blocks whose parent isn't known yet, keyed by their hash.
+type OrphanPool struct {
+	mu     sync.Mutex
+	blocks map[string]orphanBlock
+}
+
+// Add stores the block received from peer, the oldest orphan is evicted when the pool is full.
+func (p *OrphanPool) Add(block Block, peer string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.blocks == nil {
+		p.blocks = map[string]orphanBlock{}
+	}
+	p.expire()
+
+	if len(p.blocks) >= MaxOrphanBlocks {
+		var oldest string
+		for hash, orphan := range p.blocks {
+			if oldest == "" || orphan.received.Before(p.blocks[oldest].received) {
+				oldest = hash
+			}
+		}
+		delete(p.blocks, oldest)
+	}
+	p.blocks[block.Hash()] = orphanBlock{block: block, peer: peer, received: time.Now()}
+}
+
+func (p *OrphanPool) Has(hash string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.blocks[hash]
+	return ok
+}
+
+// Children removes and returns the orphans whose parent has the hash.
+func (p *OrphanPool) Children(hash string) []Block {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.expire()
+
+	var children []Block
+	for h, orphan := range p.blocks {
+		if orphan.block.PreviousHash == hash {
+			children = append(children, orphan.block)
+			delete(p.blocks, h)
+		}
+	}
+	return children
+}
+
+func (p *OrphanPool) expire() {
+	for hash, orphan := range p.blocks {
+		if time.Since(orphan.received) > OrphanExpiry {
+			delete(p.blocks, hash)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrphanPool(t *testing.T) {
+	var p OrphanPool
+	parent := Block{Index: 1, Proof: 1}
+	child := Block{Index: 2, PreviousHash: parent.Hash()}
+	other := Block{Index: 2, PreviousHash: "other"}
+	p.Add(child, "peer")
+	p.Add(other, "peer")
+
+	if !p.Has(child.Hash()) {
+		t.Fatal("orphan wasn't stored")
+	}
+	children := p.Children(parent.Hash())
+	if len(children) != 1 || children[0].Hash() != child.Hash() {
+		t.Fatalf("Children() = %v, want the child", children)
+	}
+	if p.Has(child.Hash()) || !p.Has(other.Hash()) {
+		t.Error("Children() doesn't remove exactly the children")
+	}
+
+	// make sure the other orphan is the oldest one
+	orphan := p.blocks[other.Hash()]
+	orphan.received = orphan.received.Add(-time.Minute)
+	p.blocks[other.Hash()] = orphan
+	for i := 0; i < MaxOrphanBlocks; i++ {
+		p.Add(Block{Index: int64(3 + i)}, "peer")
+	}
+	if p.Has(other.Hash()) {
+		t.Error("the oldest orphan wasn't evicted from the full pool")
+	}
+	if len(p.blocks) != MaxOrphanBlocks {
+		t.Errorf("pool holds %d orphans, want %d", len(p.blocks), MaxOrphanBlocks)
+	}
+}
+
+func TestProofOfWorkVerifySeal(t *testing.T) {
+	defer func(params NetworkParams) { Params = params }(Params)
+	Params = MainnetParams
+	chain := []Block{{Difficulty: 8}}
+
+	// the orphan is 5 blocks ahead, at most one retarget lies in between
+	tooEasy := Block{Index: 5, Difficulty: 6}
+	if err := (ProofOfWork{}).VerifySeal(chain, tooEasy); !errors.Is(err, errInvalidDifficulty) {
+		t.Errorf("VerifySeal() = %v, want %v", err, errInvalidDifficulty)
+	}
+
+	block := Block{Index: 5, Difficulty: 7}
+	for Verification.ValidProof(block) {
+		block.Proof++
+	}
+	if err := (ProofOfWork{}).VerifySeal(chain, block); !errors.Is(err, errInvalidProof) {
+		t.Errorf("VerifySeal() = %v, want %v", err, errInvalidProof)
+	}
+	if !(ProofOfWork{}).Seal(context.Background(), chain, &block) {
+		t.Fatal("sealing failed")
+	}
+	if err := (ProofOfWork{}).VerifySeal(chain, block); err != nil {
+		t.Errorf("VerifySeal() = %v, want the retargeted difficulty to be valid", err)
+	}
+}
+
+func TestConnectOrphans(t *testing.T) {
+	defer setupRegtest(t)()
+	miner := newTestWallet()
+	source := newTestBlockChain(miner)
+	for i := 0; i < 3; i++ {
+		if source.MineBlock(context.Background()) == nil {
+			t.Fatal("mining failed")
+		}
+	}
+	chain := source.Chain()
+
+	b := &BlockChain{NodeID: 1, publicKey: miner.PublicKey}
+	b.LoadData()
+	// the blocks arrive in reverse order from a node that isn't a peer
+	for _, block := range []Block{chain[3], chain[2]} {
+		if err := b.AddOrphan(block, "stranger"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !b.ResolveConflicts() {
+		t.Error("orphans of a stranger don't make the node resolve conflicts")
+	}
+	if !b.AddBlock(chain[1]) {
+		t.Fatal("the parent wasn't added")
+	}
+	b.ConnectOrphans()
+
+	if b.GetLastBlock().Hash() != chain[3].Hash() {
+		t.Errorf("tip is block %d, want the orphans connected up to block 3", b.GetLastBlock().Index)
+	}
+	if b.orphans.Has(chain[2].Hash()) || b.orphans.Has(chain[3].Hash()) {
+		t.Error("connected blocks are still orphans")
+	}
+}
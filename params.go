@@ -0,0 +1,101 @@
+package main
+
+import (
+	"time"
+)
+
+// NetworkParams are the parameters distinguishing the networks a node can run on.
+type NetworkParams struct {
+	Name        string
+	DefaultPort int
+	Genesis     Genesis
+
+	// MinDifficulty is the lowest number of leading zero bits the difficulty is retargeted to.
+	MinDifficulty uint64
+	// DifficultyAdjustmentInterval is the number of blocks after which the difficulty is retargeted.
+	DifficultyAdjustmentInterval int64
+	TargetBlockTime              time.Duration
+	// NoRetargeting keeps the difficulty of the genesis block forever.
+	NoRetargeting bool
+
+	Policy MonetaryPolicy
+
+	// MaxBlockSize is the maximum number of bytes of all transactions of a block, including the coinbase.
+	MaxBlockSize int
+	// MaxBlockTransactions is the maximum number of transactions of a block, including the coinbase.
+	MaxBlockTransactions int
+
+	// AllowGenerate enables the /generate endpoint mining blocks on request.
+	AllowGenerate bool
+}
+
+var (
+	MainnetParams = NetworkParams{
+		Name:        "mainnet",
+		DefaultPort: 5000,
+		Genesis: Genesis{
+			ChainID:    "mainnet",
+			Difficulty: 8,
+		},
+		MinDifficulty:                1,
+		DifficultyAdjustmentInterval: 10,
+		TargetBlockTime:              10 * time.Second,
+		Policy: MonetaryPolicy{
+			InitialSubsidy:  10 * Coin,
+			HalvingInterval: 100,
+			MaxSupply:       2000 * Coin,
+		},
+		MaxBlockSize:         100000,
+		MaxBlockTransactions: 100,
+	}
+
+	TestnetParams = NetworkParams{
+		Name:        "testnet",
+		DefaultPort: 15000,
+		Genesis: Genesis{
+			ChainID:    "testnet",
+			Difficulty: 4,
+		},
+		MinDifficulty:                1,
+		DifficultyAdjustmentInterval: 10,
+		TargetBlockTime:              5 * time.Second,
+		Policy: MonetaryPolicy{
+			InitialSubsidy:  10 * Coin,
+			HalvingInterval: 100,
+			MaxSupply:       2000 * Coin,
+		},
+		MaxBlockSize:         100000,
+		MaxBlockTransactions: 100,
+	}
+
+	// RegtestParams mine instantly, every proof is valid, for tests and demos.
+	RegtestParams = NetworkParams{
+		Name:        "regtest",
+		DefaultPort: 25000,
+		Genesis: Genesis{
+			ChainID:    "regtest",
+			Difficulty: 0,
+		},
+		DifficultyAdjustmentInterval: 10,
+		TargetBlockTime:              10 * time.Second,
+		NoRetargeting:                true,
+		Policy: MonetaryPolicy{
+			InitialSubsidy:  50 * Coin,
+			HalvingInterval: 150,
+			MaxSupply:       15000 * Coin,
+		},
+		MaxBlockSize:         100000,
+		MaxBlockTransactions: 100,
+		AllowGenerate:        true,
+	}
+)
+
+// Networks are the presets selectable by name.
+var Networks = map[string]NetworkParams{
+	MainnetParams.Name: MainnetParams,
+	TestnetParams.Name: TestnetParams,
+	RegtestParams.Name: RegtestParams,
+}
+
+// Params are the parameters of the network the node runs on.
+var Params = MainnetParams
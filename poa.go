@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sort"
+)
+
+const (
+	// DiffInTurn is the difficulty of a block sealed by the signer whose turn it was.
+	DiffInTurn = 2
+	// DiffNoTurn is the difficulty of a block sealed out of turn.
+	DiffNoTurn = 1
+
+	VoteAdd    = "add"
+	VoteRemove = "remove"
+)
+
+var (
+	errUnauthorizedSigner = errors.New("signer is not authorized")
+	errRecentlySigned     = errors.New("signer has signed recently")
+	errInvalidSeal        = errors.New("seal signature is invalid")
+)
+
+// ProofOfAuthority is a clique-style consensus engine, a block is sealed by a signature of one of the authorized signers.
+// Signers take turns, the signer in turn produces blocks with DiffInTurn, the others with DiffNoTurn,
+// and nobody may seal more than one of every len(signers)/2+1 consecutive blocks.
+// Signers are voted in and out by transactions with Vote set, sent by a signer for the Recipient;
+// a vote takes effect once more than half of the signers agree.
+type ProofOfAuthority struct {
+	Wallet  *Wallet
+	Signers []string
+}
+
+// LoadSigners reads the initial signers from a JSON list of public keys.
+func LoadSigners(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var signers []string
+	if err := json.NewDecoder(f).Decode(&signers); err != nil {
+		panic(err)
+	}
+	return signers
+}
+
+// SignersAt returns the sorted signers authorized to seal the block following the chain.
+func (e ProofOfAuthority) SignersAt(chain []Block) []string {
+	signers := map[string]bool{}
+	for _, signer := range e.Signers {
+		signers[signer] = true
+	}
+
+	votes := map[string]map[string]string{}
+	for _, b := range chain {
+		for _, tx := range b.Transactions {
+			if verifyVote(tx) != nil || tx.Vote == "" || !signers[tx.Sender] || (tx.Vote == VoteAdd) == signers[tx.Recipient] {
+				continue
+			}
+
+			if votes[tx.Recipient] == nil {
+				votes[tx.Recipient] = map[string]string{}
+			}
+			votes[tx.Recipient][tx.Sender] = tx.Vote
+
+			var tally int
+			for _, vote := range votes[tx.Recipient] {
+				if vote == tx.Vote {
+					tally++
+				}
+			}
+			if tally <= len(signers)/2 {
+				continue
+			}
+
+			if tx.Vote == VoteAdd {
+				signers[tx.Recipient] = true
+			} else {
+				delete(signers, tx.Recipient)
+				for _, candidateVotes := range votes {
+					delete(candidateVotes, tx.Recipient)
+				}
+			}
+			delete(votes, tx.Recipient)
+		}
+	}
+
+	sorted := make([]string, 0, len(signers))
+	for signer := range signers {
+		sorted = append(sorted, signer)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (e ProofOfAuthority) verifySigner(chain []Block, signers []string, signer string) error {
+	i := sort.SearchStrings(signers, signer)
+	if i == len(signers) || signers[i] != signer {
+		return errUnauthorizedSigner
+	}
+
+	limit := len(signers)/2 + 1
+	for j := len(chain) - 1; j > 0 && j > len(chain)-limit; j-- {
+		if chain[j].Signer == signer {
+			return errRecentlySigned
+		}
+	}
+	return nil
+}
+
+func (e ProofOfAuthority) difficulty(signers []string, index int64, signer string) uint64 {
+	if len(signers) > 0 && signers[index%int64(len(signers))] == signer {
+		return DiffInTurn
+	}
+	return DiffNoTurn
+}
+
+// sealHash returns the hash of the block without its seal signature.
+func sealHash(block Block) string {
+	block.Signature = ""
+	return block.Hash()
+}
+
+func (e ProofOfAuthority) Prepare(chain []Block, block *Block) {
+	block.Signer = e.Wallet.PublicKey
+	block.Difficulty = e.difficulty(e.SignersAt(chain), block.Index, block.Signer)
+}
+
+func (e ProofOfAuthority) Seal(ctx context.Context, chain []Block, block *Block) bool {
+	if e.verifySigner(chain, e.SignersAt(chain), block.Signer) != nil {
+		return false
+	}
+
+	block.Signature = e.Wallet.Sign(sealHash(*block))
+	return ctx.Err() == nil
+}
+
+func (e ProofOfAuthority) VerifyHeader(chain []Block, block Block) error {
+	signers := e.SignersAt(chain)
+	if err := e.verifySigner(chain, signers, block.Signer); err != nil {
+		return err
+	}
+	if block.Difficulty != e.difficulty(signers, block.Index, block.Signer) {
+		return errInvalidDifficulty
+	}
+	if !(Wallet{}).VerifySignature(block.Signer, sealHash(block), block.Signature) {
+		return errInvalidSeal
+	}
+	return nil
+}
+
+// VerifySeal checks that the block is signed by one of the signers authorized after the chain.
+func (e ProofOfAuthority) VerifySeal(chain []Block, block Block) error {
+	signers := e.SignersAt(chain)
+	if i := sort.SearchStrings(signers, block.Signer); i == len(signers) || signers[i] != block.Signer {
+		return errUnauthorizedSigner
+	}
+	if !(Wallet{}).VerifySignature(block.Signer, sealHash(block), block.Signature) {
+		return errInvalidSeal
+	}
+	return nil
+}
+
+// Work returns the difficulty of the block, so chains sealed in turn weigh more.
+func (e ProofOfAuthority) Work(block Block) *big.Int {
+	return new(big.Int).SetUint64(block.Difficulty)
+}
+
+func (e ProofOfAuthority) SelectFork(local, remote []Block) bool {
+	return HeavierChain(ChainWork(remote), remote[len(remote)-1].Hash(), ChainWork(local), local[len(local)-1].Hash())
+}
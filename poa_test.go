@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSignersAt(t *testing.T) {
+	vote := func(sender, candidate, vote string) Transaction {
+		return Transaction{Sender: sender, Recipient: candidate, Vote: vote}
+	}
+	e := ProofOfAuthority{Signers: []string{"c", "a", "b"}}
+
+	tests := []struct {
+		name  string
+		votes []Transaction
+		want  []string
+	}{
+		{"initial signers", nil, []string{"a", "b", "c"}},
+		{"add without a majority", []Transaction{vote("a", "d", VoteAdd)}, []string{"a", "b", "c"}},
+		{"add with a majority", []Transaction{vote("a", "d", VoteAdd), vote("b", "d", VoteAdd)}, []string{"a", "b", "c", "d"}},
+		{"same signer voting twice", []Transaction{vote("a", "d", VoteAdd), vote("a", "d", VoteAdd)}, []string{"a", "b", "c"}},
+		{"vote of a non-signer", []Transaction{vote("a", "d", VoteAdd), vote("x", "d", VoteAdd)}, []string{"a", "b", "c"}},
+		{"invalid vote", []Transaction{vote("a", "d", VoteAdd), vote("b", "d", "promote")}, []string{"a", "b", "c"}},
+		{"remove with a majority", []Transaction{vote("a", "c", VoteRemove), vote("b", "c", VoteRemove)}, []string{"a", "b"}},
+		{
+			"removed signer's votes are dropped",
+			[]Transaction{vote("c", "d", VoteAdd), vote("a", "c", VoteRemove), vote("b", "c", VoteRemove), vote("a", "d", VoteAdd)},
+			[]string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := []Block{{}, {Index: 1, Transactions: tt.votes}}
+			if got := e.SignersAt(chain); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SignersAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestSigners returns n wallets ordered by their public key, the order signers take turns in.
+func newTestSigners(n int) []Wallet {
+	wallets := make([]Wallet, n)
+	for i := range wallets {
+		wallets[i] = newTestWallet()
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].PublicKey < wallets[j].PublicKey })
+	return wallets
+}
+
+func TestProofOfAuthoritySealing(t *testing.T) {
+	signers := newTestSigners(3)
+	keys := []string{signers[0].PublicKey, signers[1].PublicKey, signers[2].PublicKey}
+	engine := func(w Wallet) ProofOfAuthority {
+		return ProofOfAuthority{Wallet: &w, Signers: keys}
+	}
+	seal := func(chain []Block, w Wallet) (Block, bool) {
+		block := Block{Index: int64(len(chain)), PreviousHash: chain[len(chain)-1].Hash()}
+		engine(w).Prepare(chain, &block)
+		return block, engine(w).Seal(context.Background(), chain, &block)
+	}
+	chain := []Block{{}}
+
+	// block 1 is the turn of the second signer
+	inTurn, ok := seal(chain, signers[1])
+	if !ok || inTurn.Difficulty != DiffInTurn {
+		t.Fatalf("in turn block sealed = %v with difficulty %d, want %d", ok, inTurn.Difficulty, DiffInTurn)
+	}
+	outOfTurn, ok := seal(chain, signers[0])
+	if !ok || outOfTurn.Difficulty != DiffNoTurn {
+		t.Fatalf("out of turn block sealed = %v with difficulty %d, want %d", ok, outOfTurn.Difficulty, DiffNoTurn)
+	}
+	for _, block := range []Block{inTurn, outOfTurn} {
+		if err := engine(Wallet{}).VerifyHeader(chain, block); err != nil {
+			t.Errorf("VerifyHeader() = %v for the block with difficulty %d", err, block.Difficulty)
+		}
+	}
+	if engine(Wallet{}).Work(inTurn).Cmp(engine(Wallet{}).Work(outOfTurn)) <= 0 {
+		t.Error("the block sealed in turn doesn't weigh more")
+	}
+
+	chain = append(chain, inTurn)
+	if _, ok := seal(chain, signers[1]); ok {
+		t.Error("the signer of the last block sealed the next one")
+	}
+	recent := Block{Index: 2, PreviousHash: inTurn.Hash(), Signer: signers[1].PublicKey, Difficulty: DiffNoTurn}
+	recent.Signature = signers[1].Sign(sealHash(recent))
+	if err := engine(Wallet{}).VerifyHeader(chain, recent); !errors.Is(err, errRecentlySigned) {
+		t.Errorf("VerifyHeader() = %v, want %v", err, errRecentlySigned)
+	}
+	if next, ok := seal(chain, signers[2]); !ok || next.Difficulty != DiffInTurn {
+		t.Errorf("block 2 sealed = %v with difficulty %d, want the turn of the third signer", ok, next.Difficulty)
+	}
+
+	if _, ok := seal(chain, newTestWallet()); ok {
+		t.Error("an unauthorized signer sealed a block")
+	}
+	tampered := inTurn
+	tampered.Timestamp++
+	if err := engine(Wallet{}).VerifyHeader(chain[:1], tampered); !errors.Is(err, errInvalidSeal) {
+		t.Errorf("VerifyHeader() = %v, want %v", err, errInvalidSeal)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+// MonetaryPolicy defines how many coins are issued by the coinbase of each block.
+// The subsidy starts at InitialSubsidy and halves every HalvingInterval blocks,
+// it's never paid beyond MaxSupply.
+type MonetaryPolicy struct {
+	InitialSubsidy  Amount
+	HalvingInterval int64
+	MaxSupply       Amount
+	// Premine is the supply allocated by the genesis block, it counts toward MaxSupply.
+	Premine Amount
+}
+
+// Subsidy returns the subsidy of the block with index before applying the supply cap.
+func (p MonetaryPolicy) Subsidy(index int64) Amount {
+	if index < 1 {
+		return 0
+	}
+	halvings := (index - 1) / p.HalvingInterval
+	if halvings >= 64 {
+		return 0
+	}
+	return p.InitialSubsidy >> uint(halvings)
+}
+
+// Issued returns the supply issued by the premine and the subsidies of all blocks before index.
+func (p MonetaryPolicy) Issued(index int64) Amount {
+	issued := p.Premine
+	if issued >= p.MaxSupply {
+		return p.MaxSupply
+	}
+	for era := int64(0); era < 64 && era*p.HalvingInterval < index-1; era++ {
+		blocks := index - 1 - era*p.HalvingInterval
+		if blocks > p.HalvingInterval {
+			blocks = p.HalvingInterval
+		}
+		issued += Amount(blocks) * p.Subsidy(era*p.HalvingInterval+1)
+		if issued >= p.MaxSupply {
+			return p.MaxSupply
+		}
+	}
+	return issued
+}
+
+// Reward returns the maximum amount the coinbase of the block with index may pay.
+func (p MonetaryPolicy) Reward(index int64) Amount {
+	reward := p.Subsidy(index)
+	if remaining := p.MaxSupply - p.Issued(index); remaining < reward {
+		reward = remaining
+	}
+	if reward < 0 {
+		return 0
+	}
+	return reward
+}
+
+// NextHalving returns the index of the first block after index whose subsidy is halved.
+func (p MonetaryPolicy) NextHalving(index int64) int64 {
+	if index < 1 {
+		return p.HalvingInterval + 1
+	}
+	return ((index-1)/p.HalvingInterval+1)*p.HalvingInterval + 1
+}
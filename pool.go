@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	// PoolShareDifficultyDelta is how many bits the difficulty of a share is below the one of the block.
+	PoolShareDifficultyDelta = 4
+	// PoolWindow is the number of last shares (N of PPLNS) the reward of a found block is split among.
+	PoolWindow = 100
+)
+
+var (
+	errUnknownTemplate = errors.New("template is unknown or stale")
+	errDuplicateShare  = errors.New("share was already submitted")
+	errLowShare        = errors.New("share doesn't meet the share difficulty")
+	errBlockRejected   = errors.New("block was rejected")
+)
+
+type share struct {
+	miner string
+	work  *big.Int
+}
+
+// Pool lets many miners work on the same block templates. It accepts shares of a lower difficulty than the block.
+// The coinbase of a template splits the reward among the miners of the last PoolWindow shares at the time the work
+// is issued, proportionally to the work of their shares (pay per last N shares). The proof covers the coinbase, so
+// shares found for one template can't be redirected to another payout.
+type Pool struct {
+	mu        sync.Mutex
+	tip       string
+	templates map[string]Block
+	submitted map[string]struct{}
+	shares    []share
+}
+
+// ShareDifficulty returns the difficulty of the shares for a block with difficulty.
+func ShareDifficulty(difficulty uint64) uint64 {
+	if difficulty < PoolShareDifficultyDelta {
+		return 0
+	}
+	return difficulty - PoolShareDifficultyDelta
+}
+
+// templateID identifies a template by the data its proof covers.
+func templateID(block Block) string {
+	return HashString256(proofPrefix(block))
+}
+
+// Work returns a new block template for the pool miners paying the current share window.
+// The reward goes to the wallet of the node as long as there are no shares yet.
+func (p *Pool) Work(b *BlockChain) (string, Block, bool) {
+	template, ok := b.BlockTemplate(b.PublicKey())
+	if !ok {
+		return "", Block{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tip != template.PreviousHash {
+		p.tip = template.PreviousHash
+		p.templates = map[string]Block{}
+		p.submitted = map[string]struct{}{}
+	}
+	if len(p.shares) > 0 {
+		template.Transactions[len(template.Transactions)-1] = p.coinbase(template)
+	}
+
+	id := templateID(template)
+	p.templates[id] = template
+	return id, template, true
+}
+
+// SubmitShare records the share of the miner for the template and submits the block if the share meets its difficulty.
+// It returns whether a block was found. The pool isn't locked while the block is submitted and broadcast.
+func (p *Pool) SubmitShare(b *BlockChain, miner, id string, proof uint64) (bool, error) {
+	block, found, err := p.recordShare(b, miner, id, proof)
+	if err != nil || !found {
+		return false, err
+	}
+	if !b.SubmitBlock(block) {
+		return false, errBlockRejected
+	}
+	return true, nil
+}
+
+// recordShare records the share of the miner for the template, it returns the sealed block if the share meets
+// the block difficulty.
+func (p *Pool) recordShare(b *BlockChain, miner, id string, proof uint64) (Block, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	template, ok := p.templates[id]
+	if !ok || template.PreviousHash != b.GetLastBlock().Hash() {
+		return Block{}, false, errUnknownTemplate
+	}
+	key := id + strconv.FormatUint(proof, 10)
+	if _, ok := p.submitted[key]; ok {
+		return Block{}, false, errDuplicateShare
+	}
+
+	shareDifficulty := ShareDifficulty(template.Difficulty)
+	h := HashString256(proofPrefix(template) + strconv.FormatUint(proof, 10))
+	if !HashMeetsDifficulty(h, shareDifficulty) {
+		return Block{}, false, errLowShare
+	}
+
+	p.submitted[key] = struct{}{}
+	p.shares = append(p.shares, share{miner: miner, work: ProofOfWork{}.Work(Block{Difficulty: shareDifficulty})})
+	if len(p.shares) > PoolWindow {
+		p.shares = p.shares[len(p.shares)-PoolWindow:]
+	}
+
+	if !HashMeetsDifficulty(h, template.Difficulty) {
+		return Block{}, false, nil
+	}
+	block := template
+	block.Proof = proof
+	return block, true, nil
+}
+
+// coinbase splits the reward of the template among the miners of the share window.
+// Payouts are rounded down to base units so that rounding never exceeds the reward.
+func (p *Pool) coinbase(template Block) Transaction {
+	reward := template.Transactions[len(template.Transactions)-1].Amount
+
+	total := new(big.Int)
+	work := map[string]*big.Int{}
+	for _, s := range p.shares {
+		if work[s.miner] == nil {
+			work[s.miner] = new(big.Int)
+		}
+		work[s.miner].Add(work[s.miner], s.work)
+		total.Add(total, s.work)
+	}
+
+	miners := make([]string, 0, len(work))
+	for miner := range work {
+		miners = append(miners, miner)
+	}
+	sort.Strings(miners)
+
+	coinbase := Transaction{Sender: MiningSender, Nonce: uint64(template.Index)}
+	for _, miner := range miners {
+		amount := new(big.Int).Mul(big.NewInt(int64(reward)), work[miner])
+		amount.Quo(amount, total)
+		coinbase.Outputs = append(coinbase.Outputs, Output{Recipient: miner, Amount: Amount(amount.Int64())})
+	}
+	return coinbase
+}
+
+// Stats returns the work of each miner within the share window.
+func (p *Pool) Stats() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	work := map[string]*big.Int{}
+	for _, s := range p.shares {
+		if work[s.miner] == nil {
+			work[s.miner] = new(big.Int)
+		}
+		work[s.miner].Add(work[s.miner], s.work)
+	}
+
+	stats := make(map[string]string, len(work))
+	for miner, w := range work {
+		stats[miner] = w.String()
+	}
+	return stats
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+// findProof returns the first proof from start on for the template whose hash meets the share difficulty,
+// and the block difficulty only if block is set.
+func findProof(template Block, start uint64, block bool) uint64 {
+	prefix := proofPrefix(template)
+	for proof := start; ; proof++ {
+		h := HashString256(prefix + strconv.FormatUint(proof, 10))
+		if HashMeetsDifficulty(h, ShareDifficulty(template.Difficulty)) && HashMeetsDifficulty(h, template.Difficulty) == block {
+			return proof
+		}
+	}
+}
+
+func TestPoolPayouts(t *testing.T) {
+	defer setupRegtest(t)()
+	GenesisBlock = Genesis{ChainID: "regtest", Difficulty: 6}.Block()
+	node := newTestWallet()
+	b := newTestBlockChain(node)
+	var p Pool
+
+	id, template, ok := p.Work(b)
+	if !ok {
+		t.Fatal("no work")
+	}
+	if coinbase := template.Transactions[len(template.Transactions)-1]; coinbase.Recipient != node.PublicKey {
+		t.Errorf("coinbase pays %q before any share, want the node", coinbase.Recipient)
+	}
+
+	// alice submits three shares, bob one
+	var proof uint64
+	for _, miner := range []string{"alice", "alice", "alice", "bob"} {
+		proof = findProof(template, proof+1, false)
+		if found, err := p.SubmitShare(b, miner, id, proof); found || err != nil {
+			t.Fatalf("SubmitShare() = %v, %v, want a share", found, err)
+		}
+	}
+	if _, err := p.SubmitShare(b, "bob", id, proof); !errors.Is(err, errDuplicateShare) {
+		t.Errorf("SubmitShare() = %v, want %v", err, errDuplicateShare)
+	}
+	low := proof + 1
+	for HashMeetsDifficulty(HashString256(proofPrefix(template)+strconv.FormatUint(low, 10)), ShareDifficulty(template.Difficulty)) {
+		low++
+	}
+	if _, err := p.SubmitShare(b, "bob", id, low); !errors.Is(err, errLowShare) {
+		t.Errorf("SubmitShare() = %v, want %v", err, errLowShare)
+	}
+	if _, err := p.SubmitShare(b, "bob", "unknown", proof); !errors.Is(err, errUnknownTemplate) {
+		t.Errorf("SubmitShare() = %v, want %v", err, errUnknownTemplate)
+	}
+
+	id, template, _ = p.Work(b)
+	reward := Params.Policy.Reward(1)
+	want := []Output{{"alice", reward * 3 / 4}, {"bob", reward / 4}}
+	coinbase := template.Transactions[len(template.Transactions)-1]
+	if len(coinbase.Outputs) != len(want) || coinbase.Outputs[0] != want[0] || coinbase.Outputs[1] != want[1] {
+		t.Fatalf("coinbase pays %v, want %v", coinbase.Outputs, want)
+	}
+
+	found, err := p.SubmitShare(b, "bob", id, findProof(template, 0, true))
+	if !found || err != nil {
+		t.Fatalf("SubmitShare() = %v, %v, want a block", found, err)
+	}
+	if b.GetBalanceWithSender("alice") != want[0].Amount || b.GetBalanceWithSender("bob") != want[1].Amount {
+		t.Errorf("balances are %s and %s, want %s and %s",
+			b.GetBalanceWithSender("alice"), b.GetBalanceWithSender("bob"), want[0].Amount, want[1].Amount)
+	}
+	if _, err := p.SubmitShare(b, "bob", id, proof); !errors.Is(err, errUnknownTemplate) {
+		t.Errorf("SubmitShare() = %v for a stale template, want %v", err, errUnknownTemplate)
+	}
+}
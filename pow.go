@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// hashesTried counts the proofs tried by all ProofOfWork.Seal calls, to measure the hashrate.
+var hashesTried uint64
+
+var (
+	errInvalidDifficulty = errors.New("difficulty is invalid")
+	errInvalidProof      = errors.New("proof of work is invalid")
+)
+
+// proofPrefix returns the data hashed together with the proof of the block, the JSON encoding of the whole block
+// with a zero proof. The proof commits to every field, including the coinbase, the index and the timestamp.
+func proofPrefix(block Block) string {
+	block.Proof = 0
+	b, _ := json.Marshal(block)
+	return string(b)
+}
+
+// ProofOfWork is the default consensus engine, a block is sealed by finding a proof
+// whose hash has as many leading zero bits as the retargeted difficulty requires.
+type ProofOfWork struct{}
+
+func (ProofOfWork) Prepare(chain []Block, block *Block) {
+	block.Difficulty = NextDifficulty(chain)
+}
+
+// Seal searches for the proof using all CPU cores, each worker tries every n-th proof.
+func (ProofOfWork) Seal(ctx context.Context, chain []Block, block *Block) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	prefix := proofPrefix(*block)
+	workers := runtime.NumCPU()
+	found := make(chan uint64, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(proof uint64) {
+			defer wg.Done()
+			for n := uint64(1); ; n++ {
+				if HashMeetsDifficulty(HashString256(prefix+strconv.FormatUint(proof, 10)), block.Difficulty) {
+					atomic.AddUint64(&hashesTried, n%1024)
+					select {
+					case found <- proof:
+					default:
+					}
+					cancel()
+					return
+				}
+				if n%1024 == 0 {
+					atomic.AddUint64(&hashesTried, 1024)
+					if ctx.Err() != nil {
+						return
+					}
+				}
+				proof += uint64(workers)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	select {
+	case block.Proof = <-found:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ProofOfWork) VerifyHeader(chain []Block, block Block) error {
+	if block.Difficulty != NextDifficulty(chain) {
+		return errInvalidDifficulty
+	}
+	if !Verification.ValidProof(block) {
+		return errInvalidProof
+	}
+	return nil
+}
+
+// VerifySeal checks the proof against the difficulty of the block. The difficulty can't be lower than the one
+// expected after the chain less one bit for each retarget between the chain and the block.
+func (ProofOfWork) VerifySeal(chain []Block, block Block) error {
+	next := NextDifficulty(chain)
+	floor := Params.MinDifficulty
+	if next < floor || Params.NoRetargeting {
+		floor = next
+	}
+	distance := block.Index - int64(len(chain))
+	if distance < 0 {
+		distance = -distance
+	}
+	if drop := uint64(distance/Params.DifficultyAdjustmentInterval + 1); !Params.NoRetargeting && next-floor > drop {
+		floor = next - drop
+	}
+
+	if block.Difficulty < floor {
+		return errInvalidDifficulty
+	}
+	if !Verification.ValidProof(block) {
+		return errInvalidProof
+	}
+	return nil
+}
+
+// Work returns the expected number of hashes it took to find the proof of the block.
+func (ProofOfWork) Work(block Block) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(block.Difficulty))
+}
+
+func (ProofOfWork) SelectFork(local, remote []Block) bool {
+	return HeavierChain(ChainWork(remote), remote[len(remote)-1].Hash(), ChainWork(local), local[len(local)-1].Hash())
+}
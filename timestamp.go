@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// MedianTimeSpan is the number of previous blocks whose median timestamp a new block has to exceed.
+	MedianTimeSpan = 11
+	// MaxFutureBlockTime is how far ahead of the node clock a block timestamp may be.
+	MaxFutureBlockTime = 2 * time.Hour
+)
+
+// MedianTimePast returns the median timestamp of the last MedianTimeSpan blocks of the chain.
+func MedianTimePast(chain []Block) int64 {
+	start := len(chain) - MedianTimeSpan
+	if start < 0 {
+		start = 0
+	}
+
+	timestamps := make([]int64, 0, MedianTimeSpan)
+	for _, b := range chain[start:] {
+		timestamps = append(timestamps, b.Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// NextTimestamp returns the timestamp for a block mined on top of the chain,
+// the node clock unless it's not past the median time of the chain.
+func NextTimestamp(chain []Block) int64 {
+	now := time.Now().Unix()
+	if mtp := MedianTimePast(chain); now <= mtp {
+		return mtp + 1
+	}
+	return now
+}
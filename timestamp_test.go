@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func chainWithTimestamps(timestamps ...int64) []Block {
+	chain := make([]Block, len(timestamps))
+	for i, ts := range timestamps {
+		chain[i] = Block{Index: int64(i), Timestamp: ts}
+	}
+	return chain
+}
+
+func TestMedianTimePast(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain []Block
+		want  int64
+	}{
+		{"genesis only", chainWithTimestamps(5), 5},
+		{"unordered", chainWithTimestamps(1, 9, 3, 7, 5), 5},
+		{"even number of blocks", chainWithTimestamps(1, 2, 3, 4), 3},
+		// only the last 11 blocks count, the early outliers are ignored
+		{"longer than the span", chainWithTimestamps(100, 100, 100, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11), 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MedianTimePast(tt.chain); got != tt.want {
+				t.Errorf("MedianTimePast() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidTimestamp(t *testing.T) {
+	now := time.Now().Unix()
+	chain := chainWithTimestamps(now-50, now-40, now-30)
+	maxFuture := int64(MaxFutureBlockTime / time.Second)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		want      bool
+	}{
+		{"after the median", now - 39, true},
+		{"at the median", now - 40, false},
+		{"before the median", now - 45, false},
+		{"before the last block", now - 35, true},
+		{"at the max drift", now + maxFuture - 1, true},
+		{"beyond the max drift", now + maxFuture + 60, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verification.ValidTimestamp(chain, tt.timestamp); got != tt.want {
+				t.Errorf("ValidTimestamp(%d) = %v, want %v", tt.timestamp-now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTimestamp(t *testing.T) {
+	now := time.Now().Unix()
+	if ts := NextTimestamp(chainWithTimestamps(now - 100)); ts < now {
+		t.Errorf("NextTimestamp() = %d, want the clock %d", ts, now)
+	}
+
+	ahead := now + 600
+	if ts := NextTimestamp(chainWithTimestamps(ahead, ahead, ahead)); ts != ahead+1 {
+		t.Errorf("NextTimestamp() = %d, want one second past the median %d", ts, ahead)
+	}
+}
@@ -1,8 +1,111 @@
 package main
 
+import "encoding/json"
+
+// Output pays an amount to a recipient, a transaction may pay several at once.
+type Output struct {
+	Recipient string `json:"recipient"`
+	Amount    Amount `json:"amount"`
+}
+
 type Transaction struct {
-	Sender    string  `json:"sender"`
-	Recipient string  `json:"recipient"`
-	Amount    float64 `json:"amount"`
-	Signature string  `json:"signature"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount    Amount `json:"amount"`
+	Fee       Amount `json:"fee"`
+	Nonce     uint64 `json:"nonce"`
+	// LockTime is the block index or timestamp before which the transaction can't be mined, see IsFinal.
+	LockTime  int64      `json:"lock_time,omitempty"`
+	Signature string     `json:"signature"`
+	Vote      string     `json:"vote,omitempty"`
+	Inputs    []OutPoint `json:"inputs,omitempty"`
+	Outputs   []Output   `json:"outputs,omitempty"`
+	// Multisig defines the account of Sender when it's a multisignature account, Signatures are then
+	// the signatures of its public keys instead of Signature.
+	Multisig   *Multisig         `json:"multisig,omitempty"`
+	Signatures map[string]string `json:"signatures,omitempty"`
+	// HTLC locks an amount of the sender in a contract identified by the ID of the transaction,
+	// Contract is the ID of the contract the transaction redeems, with the Preimage when claiming it.
+	HTLC     *HTLC  `json:"htlc,omitempty"`
+	Contract string `json:"contract,omitempty"`
+	Preimage string `json:"preimage,omitempty"`
+}
+
+// SigningPayload returns the data of the transaction covered by its signature, the JSON encoding of all its
+// fields but the signatures so that no two different transactions share a payload.
+// The nonce is covered so that a signed transaction can't be replayed.
+func (tx Transaction) SigningPayload() string {
+	tx.Signature = ""
+	tx.Signatures = nil
+	b, _ := json.Marshal(tx)
+	return string(b)
+}
+
+// Size returns the number of bytes the transaction takes in a block.
+func (tx Transaction) Size() int {
+	b, _ := json.Marshal(tx)
+	return len(b)
+}
+
+// FeeRate returns the fee paid per byte of the transaction.
+func (tx Transaction) FeeRate() float64 {
+	return float64(tx.Fee) / float64(tx.Size())
+}
+
+// Payouts returns the outputs of the transaction, or the single payment to Recipient if there are none.
+func (tx Transaction) Payouts() []Output {
+	if len(tx.Outputs) > 0 {
+		return tx.Outputs
+	}
+	return []Output{{Recipient: tx.Recipient, Amount: tx.Amount}}
+}
+
+// Total returns the sum of all payouts, it fails if a payout is negative or they add up to more than the max supply.
+func (tx Transaction) Total() (Amount, error) {
+	var total Amount
+	for _, out := range tx.Payouts() {
+		var ok bool
+		if total, ok = addAmount(total, out.Amount); !ok {
+			return 0, errInvalidOutput
+		}
+	}
+	return total, nil
+}
+
+// Cost returns what the transaction spends, its payouts, the fee and the amount locked by its HTLC.
+// It fails if one of them is negative or they add up to more than the max supply.
+func (tx Transaction) Cost() (Amount, error) {
+	cost, err := tx.Total()
+	if err != nil {
+		return 0, err
+	}
+	var ok bool
+	if cost, ok = addAmount(cost, tx.Fee); !ok {
+		return 0, errInvalidAmount
+	}
+	if tx.HTLC != nil {
+		if cost, ok = addAmount(cost, tx.HTLC.Amount); !ok {
+			return 0, errInvalidAmount
+		}
+	}
+	return cost, nil
+}
+
+// ID returns the hash identifying the transaction, it covers all fields including the signature.
+func (tx Transaction) ID() string {
+	j, _ := json.Marshal(tx)
+	return HashString256(string(j))
+}
+
+func (tx Transaction) Equal(other Transaction) bool {
+	return tx.ID() == other.ID()
+}
+
+func containsTransaction(transactions []Transaction, tx Transaction) bool {
+	for _, other := range transactions {
+		if other.Equal(tx) {
+			return true
+		}
+	}
+	return false
 }
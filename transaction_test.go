@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSigningPayloadUnambiguous(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Transaction
+	}{
+		{
+			"sender and recipient boundary",
+			Transaction{Sender: "ab", Recipient: "c"},
+			Transaction{Sender: "a", Recipient: "bc"},
+		},
+		{
+			"amount and fee boundary",
+			Transaction{Sender: "a", Recipient: "b", Amount: 12, Fee: 3},
+			Transaction{Sender: "a", Recipient: "b", Amount: 1, Fee: 23},
+		},
+		{
+			"fee and nonce boundary",
+			Transaction{Sender: "a", Recipient: "b", Amount: 1, Fee: 12, Nonce: 3},
+			Transaction{Sender: "a", Recipient: "b", Amount: 1, Fee: 1, Nonce: 23},
+		},
+		{
+			"nonce and vote",
+			Transaction{Sender: "a", Recipient: "b", Nonce: 1, Vote: "1add"},
+			Transaction{Sender: "a", Recipient: "b", Nonce: 11, Vote: "add"},
+		},
+		{
+			"outputs",
+			Transaction{Sender: "a", Outputs: []Output{{Recipient: "b1", Amount: 2}}},
+			Transaction{Sender: "a", Outputs: []Output{{Recipient: "b", Amount: 12}}},
+		},
+		{
+			"inputs and outputs",
+			Transaction{Sender: "a", Inputs: []OutPoint{{TxID: "x", Index: 1}}, Outputs: []Output{{Recipient: "b", Amount: 2}}},
+			Transaction{Sender: "a", Outputs: []Output{{Recipient: "x", Amount: 1}, {Recipient: "b", Amount: 2}}},
+		},
+		{
+			"lock time",
+			Transaction{Sender: "a", Recipient: "b", LockTime: 5},
+			Transaction{Sender: "a", Recipient: "b", Vote: "lock5"},
+		},
+		{
+			"htlc",
+			Transaction{Sender: "a", HTLC: &HTLC{Recipient: "b", Hashlock: "c", Expiry: 1, Amount: 2}},
+			Transaction{Sender: "a", HTLC: &HTLC{Recipient: "bc", Expiry: 1, Amount: 2}},
+		},
+		{
+			"contract and preimage boundary",
+			Transaction{Sender: "a", Contract: "ab", Preimage: "cd"},
+			Transaction{Sender: "a", Contract: "abc", Preimage: "d"},
+		},
+		{
+			"multisig",
+			Transaction{Sender: "a", Multisig: &Multisig{Threshold: 1, PublicKeys: []string{"k1"}}},
+			Transaction{Sender: "a", Multisig: &Multisig{Threshold: 1, PublicKeys: []string{"k2"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a.SigningPayload() == tt.b.SigningPayload() {
+				t.Errorf("different transactions share the payload %s", tt.a.SigningPayload())
+			}
+		})
+	}
+}
+
+func TestSigningPayloadExcludesSignatures(t *testing.T) {
+	tx := Transaction{Sender: "a", Recipient: "b", Amount: 1}
+	signed := tx
+	signed.Signature = "signature"
+	signed.Signatures = map[string]string{"key": "signature"}
+	if tx.SigningPayload() != signed.SigningPayload() {
+		t.Error("payload changes with the signatures")
+	}
+}
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   Transaction
+		want Amount
+		err  error
+	}{
+		{"payment", Transaction{Recipient: "b", Amount: 10, Fee: 2}, 12, nil},
+		{"outputs", Transaction{Outputs: []Output{{Recipient: "b", Amount: 10}, {Recipient: "c", Amount: 5}}, Fee: 1}, 16, nil},
+		{"htlc", Transaction{HTLC: &HTLC{Amount: 7}, Fee: 1}, 8, nil},
+		{"fee only", Transaction{Fee: 3}, 3, nil},
+		{"max supply", Transaction{Recipient: "b", Amount: Params.Policy.MaxSupply - 1, Fee: 1}, Params.Policy.MaxSupply, nil},
+		{"above max supply", Transaction{Recipient: "b", Amount: Params.Policy.MaxSupply, Fee: 1}, 0, errInvalidAmount},
+		{"fee overflowing", Transaction{Recipient: "b", Amount: 10, Fee: math.MaxInt64}, 0, errInvalidAmount},
+		{"htlc overflowing", Transaction{HTLC: &HTLC{Amount: math.MaxInt64}, Fee: 1}, 0, errInvalidAmount},
+		{"negative fee", Transaction{Recipient: "b", Amount: 10, Fee: -1}, 0, errInvalidAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tx.Cost()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Cost() = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Cost() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
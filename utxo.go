@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	errMissingInputs   = errors.New("transaction spends no inputs")
+	errUnknownOutput   = errors.New("input spends an unknown or already spent output")
+	errForeignOutput   = errors.New("input spends an output of another account")
+	errUnbalanced      = errors.New("inputs don't add up to the outputs and the fee")
+	errDuplicateOutput = errors.New("transaction creates outputs which are unspent already")
+)
+
+// OutPoint references an output of a transaction by the transaction ID and the position among its payouts.
+type OutPoint struct {
+	TxID  string `json:"tx_id"`
+	Index int    `json:"index"`
+}
+
+// UTXOSet tracks the unspent transaction outputs of the chain.
+// A transaction spends whole outputs of its sender as inputs, whatever isn't paid out or left as fee
+// has to be paid back to the sender as change.
+type UTXOSet struct {
+	outputs   map[OutPoint]Output
+	contracts map[string]Contract
+	// spent and redeemed keep what was spent since the set was created, to restore it when undoing blocks.
+	spent    map[OutPoint]Output
+	redeemed map[string]Contract
+	// index is the index of the block the transactions applied next are mined in.
+	index int64
+}
+
+func NewUTXOSet() *UTXOSet {
+	return &UTXOSet{
+		outputs:   map[OutPoint]Output{},
+		contracts: map[string]Contract{},
+		spent:     map[OutPoint]Output{},
+		redeemed:  map[string]Contract{},
+	}
+}
+
+func (s *UTXOSet) Clone() LedgerState {
+	c := NewUTXOSet()
+	for op, out := range s.outputs {
+		c.outputs[op] = out
+	}
+	for id, contract := range s.contracts {
+		c.contracts[id] = contract
+	}
+	c.index = s.index
+	return c
+}
+
+func (s *UTXOSet) Balance(account string) Amount {
+	var balance Amount
+	for _, out := range s.outputs {
+		if out.Recipient == account {
+			balance += out.Amount
+		}
+	}
+	return balance
+}
+
+// Nonce is always zero, every transaction spends outputs or a contract and neither can be spent again,
+// so there is nothing to replay.
+func (s *UTXOSet) Nonce(account string) uint64 {
+	return 0
+}
+
+func (s *UTXOSet) Contract(id string) (Contract, bool) {
+	c, ok := s.contracts[id]
+	return c, ok
+}
+
+// Unspent returns the unspent outputs of the account, ordered by transaction ID and index.
+func (s *UTXOSet) Unspent(account string) []OutPoint {
+	var unspent []OutPoint
+	for op, out := range s.outputs {
+		if out.Recipient == account {
+			unspent = append(unspent, op)
+		}
+	}
+	sort.Slice(unspent, func(i, j int) bool {
+		if unspent[i].TxID != unspent[j].TxID {
+			return unspent[i].TxID < unspent[j].TxID
+		}
+		return unspent[i].Index < unspent[j].Index
+	})
+	return unspent
+}
+
+// Output returns the unspent output referenced by op.
+func (s *UTXOSet) Output(op OutPoint) (Output, bool) {
+	out, ok := s.outputs[op]
+	return out, ok
+}
+
+// Fund adds unspent outputs of the sender as inputs of the transaction until they cover its payouts and fee,
+// the payment is moved to the outputs and the rest is paid back to the sender as change.
+// A transaction paying nothing, like a vote, still spends an output so that it can't be replayed.
+// Transactions redeeming a contract are paid from the contract and need no inputs.
+func (s *UTXOSet) Fund(tx *Transaction) error {
+	need, err := tx.Cost()
+	if err != nil {
+		return err
+	}
+	if tx.Contract != "" {
+		return nil
+	}
+
+	var funds Amount
+	for _, op := range s.Unspent(tx.Sender) {
+		if funds >= need && len(tx.Inputs) > 0 {
+			break
+		}
+		tx.Inputs = append(tx.Inputs, op)
+		funds += s.outputs[op].Amount
+	}
+	if funds < need || len(tx.Inputs) == 0 {
+		return errInsufficientFunds
+	}
+
+	if total, _ := tx.Total(); total > 0 {
+		tx.Outputs = tx.Payouts()
+		tx.Recipient = ""
+		tx.Amount = 0
+	}
+	if funds > need {
+		tx.Outputs = append(tx.Outputs, Output{Recipient: tx.Sender, Amount: funds - need})
+	}
+	return nil
+}
+
+// ApplyTransaction spends the inputs of the transaction and adds its outputs to the set.
+// A transaction redeeming a contract spends the contract instead of inputs.
+// Coinbase and genesis transactions only add outputs, they have to be validated with their block.
+func (s *UTXOSet) ApplyTransaction(tx Transaction) error {
+	if tx.Sender != MiningSender && tx.Sender != GenesisSender {
+		if tx.Amount < 0 || tx.Fee < 0 {
+			return errInvalidAmount
+		}
+		if err := verifyOutputs(tx); err != nil {
+			return err
+		}
+		cost, err := tx.Cost()
+		if err != nil {
+			return err
+		}
+		if err := verifyVote(tx); err != nil {
+			return err
+		}
+		if tx.Contract == "" && len(tx.Inputs) == 0 {
+			return errMissingInputs
+		}
+		if !(Wallet{}).VerifyTransaction(tx) {
+			return errInvalidSignature
+		}
+		if err := verifyContract(tx, s.contracts, s.index); err != nil {
+			return err
+		}
+
+		var funds Amount
+		spent := map[OutPoint]bool{}
+		for _, op := range tx.Inputs {
+			out, ok := s.outputs[op]
+			if !ok || spent[op] {
+				return errUnknownOutput
+			}
+			if out.Recipient != tx.Sender {
+				return errForeignOutput
+			}
+			spent[op] = true
+			funds += out.Amount
+		}
+		if tx.Contract == "" && funds != cost {
+			return errUnbalanced
+		}
+	}
+
+	id := tx.ID()
+	for i := range tx.Payouts() {
+		if _, ok := s.outputs[OutPoint{TxID: id, Index: i}]; ok {
+			return errDuplicateOutput
+		}
+	}
+
+	for _, op := range tx.Inputs {
+		s.spent[op] = s.outputs[op]
+		delete(s.outputs, op)
+	}
+	applyContract(tx, s.contracts, s.redeemed)
+	for i, out := range tx.Payouts() {
+		if out.Amount > 0 {
+			s.outputs[OutPoint{TxID: id, Index: i}] = out
+		}
+	}
+	return nil
+}
+
+func (s *UTXOSet) ApplyBlock(block Block) error {
+	index := s.index
+	s.index = block.Index
+	for i, tx := range block.Transactions {
+		if err := s.ApplyTransaction(tx); err != nil {
+			s.undoTransactions(block.Transactions[:i])
+			s.index = index
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	s.index = block.Index + 1
+	return nil
+}
+
+func (s *UTXOSet) UndoBlock(block Block) {
+	s.undoTransactions(block.Transactions)
+	s.index = block.Index
+}
+
+// undoTransactions reverts the applied transactions in reverse order, their outputs are removed
+// and the outputs they spent are unspent again.
+func (s *UTXOSet) undoTransactions(transactions []Transaction) {
+	for i := len(transactions) - 1; i >= 0; i-- {
+		tx := transactions[i]
+		id := tx.ID()
+		for j := range tx.Payouts() {
+			delete(s.outputs, OutPoint{TxID: id, Index: j})
+		}
+		for _, op := range tx.Inputs {
+			s.outputs[op] = s.spent[op]
+			delete(s.spent, op)
+		}
+		undoContract(tx, s.contracts, s.redeemed)
+	}
+}
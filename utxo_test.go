@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestUTXOSetApplyTransaction(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	genesis := genesisTransaction(alice.PublicKey, 100)
+	funding := OutPoint{TxID: genesis.ID()}
+	spend := func(w Wallet, inputs []OutPoint, fee Amount, outputs ...Output) Transaction {
+		return signed(w, Transaction{Sender: alice.PublicKey, Fee: fee, Inputs: inputs, Outputs: outputs})
+	}
+
+	tests := []struct {
+		name string
+		tx   Transaction
+		err  error
+	}{
+		{"payment with change", spend(alice, []OutPoint{funding}, 10, Output{bob.PublicKey, 60}, Output{alice.PublicKey, 30}), nil},
+		{"fee only", spend(alice, []OutPoint{funding}, 100), nil},
+		{"fee exceeds inputs", spend(alice, []OutPoint{funding}, 20, Output{bob.PublicKey, 60}, Output{alice.PublicKey, 30}), errUnbalanced},
+		{"inputs left over", spend(alice, []OutPoint{funding}, 5, Output{bob.PublicKey, 60}, Output{alice.PublicKey, 30}), errUnbalanced},
+		{"negative fee", spend(alice, []OutPoint{funding}, -10, Output{bob.PublicKey, 110}), errInvalidAmount},
+		{"negative output", spend(alice, []OutPoint{funding}, 10, Output{bob.PublicKey, 100}, Output{alice.PublicKey, -10}), errInvalidOutput},
+		{"output above max supply", spend(alice, []OutPoint{funding}, 0, Output{bob.PublicKey, Params.Policy.MaxSupply + 1}), errInvalidOutput},
+		{"outputs overflowing", spend(alice, []OutPoint{funding}, 0, Output{bob.PublicKey, math.MaxInt64}, Output{bob.PublicKey, math.MaxInt64}, Output{alice.PublicKey, 102}), errInvalidOutput},
+		{"missing inputs", spend(alice, nil, 10, Output{bob.PublicKey, 60}), errMissingInputs},
+		{"vote", signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Vote: VoteAdd, Inputs: []OutPoint{funding}, Outputs: []Output{{alice.PublicKey, 100}}}), nil},
+		{"vote without inputs", signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Vote: VoteAdd}), errMissingInputs},
+		{"unknown output", spend(alice, []OutPoint{{TxID: "missing"}}, 0, Output{bob.PublicKey, 100}), errUnknownOutput},
+		{"output spent twice", spend(alice, []OutPoint{funding, funding}, 0, Output{bob.PublicKey, 200}), errUnknownOutput},
+		{"forged signature", spend(bob, []OutPoint{funding}, 0, Output{bob.PublicKey, 100}), errInvalidSignature},
+		{"foreign output", signed(bob, Transaction{Sender: bob.PublicKey, Inputs: []OutPoint{funding}, Outputs: []Output{{bob.PublicKey, 100}}}), errForeignOutput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewUTXOSet()
+			if err := s.ApplyBlock(Block{Transactions: []Transaction{genesis}}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := s.ApplyTransaction(tt.tx)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ApplyTransaction() = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				if _, ok := s.Output(funding); !ok || s.Balance(alice.PublicKey) != 100 {
+					t.Errorf("invalid transaction changed the set")
+				}
+				return
+			}
+			if _, ok := s.Output(funding); ok {
+				t.Errorf("spent output is still unspent")
+			}
+			var change Amount
+			for _, out := range tt.tx.Outputs {
+				if out.Recipient == alice.PublicKey {
+					change += out.Amount
+				}
+			}
+			if s.Balance(alice.PublicKey) != change {
+				t.Errorf("balance = %d, want the change %d", s.Balance(alice.PublicKey), change)
+			}
+		})
+	}
+}
+
+func TestUTXOSetRejectsReplay(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	genesis := genesisTransaction(alice.PublicKey, 100)
+	s := NewUTXOSet()
+	if err := s.ApplyBlock(Block{Transactions: []Transaction{genesis}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := signed(alice, Transaction{Sender: alice.PublicKey, Fee: 1, Inputs: []OutPoint{{TxID: genesis.ID()}}, Outputs: []Output{{bob.PublicKey, 99}}})
+	if err := s.ApplyTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ApplyTransaction(tx); !errors.Is(err, errUnknownOutput) {
+		t.Errorf("replay = %v, want %v", err, errUnknownOutput)
+	}
+
+	vote := Transaction{Sender: bob.PublicKey, Recipient: alice.PublicKey, Vote: VoteAdd}
+	if err := s.Fund(&vote); err != nil {
+		t.Fatal(err)
+	}
+	vote = signed(bob, vote)
+	if err := s.ApplyTransaction(vote); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ApplyTransaction(vote); !errors.Is(err, errUnknownOutput) {
+		t.Errorf("replayed vote = %v, want %v", err, errUnknownOutput)
+	}
+	if s.Balance(bob.PublicKey) != 99 {
+		t.Errorf("balance = %d after voting, want the change 99", s.Balance(bob.PublicKey))
+	}
+}
+
+func TestUTXOSetUndoBlock(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	preimage := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	hashlock, _ := Hashlock(preimage)
+	genesis := genesisTransaction(alice.PublicKey, 1000)
+
+	s := NewUTXOSet()
+	if err := s.ApplyBlock(Block{Transactions: []Transaction{genesis}}); err != nil {
+		t.Fatal(err)
+	}
+	genesisState := s.Clone()
+
+	lock := signed(alice, Transaction{
+		Sender:  alice.PublicKey,
+		Fee:     1,
+		Inputs:  []OutPoint{{TxID: genesis.ID()}},
+		Outputs: []Output{{alice.PublicKey, 899}},
+		HTLC:    &HTLC{Recipient: bob.PublicKey, Hashlock: hashlock, Expiry: 10, Amount: 100},
+	})
+	block1 := Block{Index: 1, Transactions: []Transaction{lock, {Sender: MiningSender, Recipient: bob.PublicKey, Amount: 51, Nonce: 1}}}
+	if err := s.ApplyBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	block1State := s.Clone()
+
+	block2 := Block{Index: 2, Transactions: []Transaction{
+		signed(bob, Transaction{Sender: bob.PublicKey, Recipient: bob.PublicKey, Amount: 99, Fee: 1, Contract: lock.ID(), Preimage: preimage}),
+		signed(alice, Transaction{Sender: alice.PublicKey, Fee: 2, Inputs: []OutPoint{{TxID: lock.ID()}}, Outputs: []Output{{bob.PublicKey, 897}}}),
+		{Sender: MiningSender, Recipient: bob.PublicKey, Amount: 53, Nonce: 2},
+	}}
+	if err := s.ApplyBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+
+	s.UndoBlock(block2)
+	if !reflect.DeepEqual(s.Clone(), block1State) {
+		t.Errorf("undoing block 2 gives %+v, want %+v", s.Clone(), block1State)
+	}
+	s.UndoBlock(block1)
+	if !reflect.DeepEqual(s.Clone(), genesisState) {
+		t.Errorf("undoing block 1 gives %+v, want %+v", s.Clone(), genesisState)
+	}
+}
@@ -1,47 +1,166 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 var Verification struct {
-	ValidProof         func(tx []Transaction, lastHash string, proof uint64) bool
-	VerifyChain        func(chain []Block) bool
-	VerifyTransaction  func(tx Transaction, getBalance func(string) float64) bool
+	ValidProof         func(block Block) bool
+	VerifyChain        func(chain []Block) error
+	VerifyTransaction  func(tx Transaction, getBalance func(string) Amount) bool
 	VerifyTransactions func(openTransactions []Transaction) bool
+	ValidTimestamp     func(chain []Block, timestamp int64) bool
+	VerifyCoinbase     func(block Block) error
+	VerifyBlockLimits  func(block Block) error
+	VerifyLockTimes    func(chain []Block, block Block) error
 }
 
+// ChainError reports the first invalid block found by Verification.VerifyChain.
+type ChainError struct {
+	Index int64
+	Err   error
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("block %d is invalid: %v", e.Index, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errGenesisMismatch     = errors.New("genesis block differs")
+	errInvalidIndex        = errors.New("block index doesn't follow the previous block")
+	errBrokenLink          = errors.New("previous hash doesn't match the previous block")
+	errInvalidTimestamp    = errors.New("timestamp is invalid")
+	errMissingCoinbase     = errors.New("block has no coinbase transaction")
+	errMisplacedCoinbase   = errors.New("coinbase transaction is not the last transaction of the block")
+	errMalformedCoinbase   = errors.New("coinbase transaction pays a fee, votes, spends inputs or a contract or has an invalid payout")
+	errInvalidCoinbase     = errors.New("coinbase transaction pays more than the block reward and fees")
+	errCoinbaseNonce       = errors.New("coinbase nonce isn't the block index")
+	errGenesisTransaction  = errors.New("genesis transaction outside of the genesis block")
+	errTooManyTransactions = errors.New("block has too many transactions")
+	errBlockTooLarge       = errors.New("block is too large")
+	errNonFinalTransaction = errors.New("block contains a transaction whose lock time has not passed")
+)
+
 func init() {
-	Verification.ValidProof = func(tx []Transaction, lastHash string, proof uint64) bool {
-		b, _ := json.Marshal(tx)
-		guess := string(b) + lastHash + strconv.FormatUint(proof, 10)
-		h := HashString256(guess)
-		fmt.Println(h)
-		return h[:2] == "00"
-	}
-	Verification.VerifyChain = func(chain []Block) bool {
+	Verification.ValidProof = func(block Block) bool {
+		guess := proofPrefix(block) + strconv.FormatUint(block.Proof, 10)
+		return HashMeetsDifficulty(HashString256(guess), block.Difficulty)
+	}
+	Verification.VerifyChain = func(chain []Block) error {
+		ledger := NewLedgerState()
 		for i, b := range chain {
 			if i == 0 {
+				if b.Hash() != GenesisBlock.Hash() {
+					return &ChainError{Index: 0, Err: errGenesisMismatch}
+				}
+				_ = ledger.ApplyBlock(b)
 				continue
 			}
 
+			if b.Index != int64(i) {
+				return &ChainError{Index: int64(i), Err: errInvalidIndex}
+			}
 			if b.PreviousHash != chain[i-1].Hash() {
-				return false
+				return &ChainError{Index: int64(i), Err: errBrokenLink}
 			}
-			if !Verification.ValidProof(b.Transactions[:len(b.Transactions)-1], b.PreviousHash, b.Proof) {
-				fmt.Println("Proof of work is invalid")
-				return false
+			if err := Verification.VerifyBlockLimits(b); err != nil {
+				return &ChainError{Index: int64(i), Err: err}
+			}
+			if err := Verification.VerifyCoinbase(b); err != nil {
+				return &ChainError{Index: int64(i), Err: err}
+			}
+			if !Verification.ValidTimestamp(chain[:i], b.Timestamp) {
+				return &ChainError{Index: int64(i), Err: errInvalidTimestamp}
+			}
+			if err := Consensus.VerifyHeader(chain[:i], b); err != nil {
+				return &ChainError{Index: int64(i), Err: err}
+			}
+			if err := Verification.VerifyLockTimes(chain[:i], b); err != nil {
+				return &ChainError{Index: int64(i), Err: err}
+			}
+			if err := ledger.ApplyBlock(b); err != nil {
+				return &ChainError{Index: int64(i), Err: err}
 			}
 		}
-		return true
+		return nil
+	}
+	Verification.ValidTimestamp = func(chain []Block, timestamp int64) bool {
+		return timestamp > MedianTimePast(chain) && timestamp <= time.Now().Add(MaxFutureBlockTime).Unix()
+	}
+	Verification.VerifyLockTimes = func(chain []Block, block Block) error {
+		for _, tx := range block.Transactions {
+			if tx.LockTime < 0 || !tx.IsFinalAfter(chain) {
+				return errNonFinalTransaction
+			}
+		}
+		return nil
+	}
+	Verification.VerifyCoinbase = func(block Block) error {
+		if len(block.Transactions) == 0 {
+			return errMissingCoinbase
+		}
+		for _, tx := range block.Transactions {
+			if tx.Sender == GenesisSender {
+				return errGenesisTransaction
+			}
+		}
+		for _, tx := range block.Transactions[:len(block.Transactions)-1] {
+			if tx.Sender == MiningSender {
+				return errMisplacedCoinbase
+			}
+		}
+
+		coinbase := block.Transactions[len(block.Transactions)-1]
+		if coinbase.Sender != MiningSender {
+			return errMissingCoinbase
+		}
+		// the nonce makes coinbase transactions paying the same to the same recipient distinct
+		if coinbase.Nonce != uint64(block.Index) {
+			return errCoinbaseNonce
+		}
+		if coinbase.Fee != 0 || coinbase.Vote != "" || len(coinbase.Inputs) > 0 || coinbase.HTLC != nil || coinbase.Contract != "" || (len(coinbase.Outputs) > 0 && (coinbase.Recipient != "" || coinbase.Amount != 0)) {
+			return errMalformedCoinbase
+		}
+		for _, out := range coinbase.Payouts() {
+			if out.Recipient == "" || out.Amount < 0 {
+				return errMalformedCoinbase
+			}
+		}
+		fees, err := BlockFees(block)
+		if err != nil {
+			return err
+		}
+		if total, err := coinbase.Total(); err != nil || total > Params.Policy.Reward(block.Index)+fees {
+			return errInvalidCoinbase
+		}
+		return nil
+	}
+	Verification.VerifyBlockLimits = func(block Block) error {
+		if len(block.Transactions) > Params.MaxBlockTransactions {
+			return errTooManyTransactions
+		}
+		var size int
+		for _, tx := range block.Transactions {
+			size += tx.Size()
+		}
+		if size > Params.MaxBlockSize {
+			return errBlockTooLarge
+		}
+		return nil
 	}
-	Verification.VerifyTransaction = func(tx Transaction, getBalance func(string) float64) bool {
+	Verification.VerifyTransaction = func(tx Transaction, getBalance func(string) Amount) bool {
 		if getBalance == nil {
 			return (Wallet{}).VerifyTransaction(tx)
 		}
-		return getBalance(tx.Sender) >= tx.Amount && (Wallet{}).VerifyTransaction(tx)
+		cost, err := tx.Cost()
+		return tx.Amount >= 0 && tx.Fee >= 0 && err == nil && (tx.Contract != "" || getBalance(tx.Sender) >= cost) && (Wallet{}).VerifyTransaction(tx)
 	}
 	Verification.VerifyTransactions = func(openTransactions []Transaction) bool {
 		for _, tx := range openTransactions {
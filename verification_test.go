@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestVerifyCoinbase(t *testing.T) {
+	defer setupRegtest(t)()
+	alice, bob := newTestWallet(), newTestWallet()
+	reward := Params.Policy.Reward(1)
+	pay := signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Fee: 5})
+	coinbase := func(amount, fee Amount) Transaction {
+		return Transaction{Sender: MiningSender, Recipient: bob.PublicKey, Amount: amount, Fee: fee, Nonce: 1}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		err          error
+	}{
+		{"reward", []Transaction{coinbase(reward, 0)}, nil},
+		{"reward and fees", []Transaction{pay, coinbase(reward+5, 0)}, nil},
+		{"less than reward", []Transaction{coinbase(reward-1, 0)}, nil},
+		{"outputs", []Transaction{{Sender: MiningSender, Nonce: 1, Outputs: []Output{{alice.PublicKey, reward - 1}, {bob.PublicKey, 1}}}}, nil},
+		{"more than reward", []Transaction{coinbase(reward+1, 0)}, errInvalidCoinbase},
+		{"more than reward and fees", []Transaction{pay, coinbase(reward+6, 0)}, errInvalidCoinbase},
+		{"minting its own fee", []Transaction{coinbase(reward+1000, 1000)}, errMalformedCoinbase},
+		{"outputs overflowing", []Transaction{{Sender: MiningSender, Nonce: 1, Outputs: []Output{{alice.PublicKey, math.MaxInt64}, {alice.PublicKey, math.MaxInt64}, {bob.PublicKey, reward + 2}}}}, errInvalidCoinbase},
+		{"negative output", []Transaction{{Sender: MiningSender, Nonce: 1, Outputs: []Output{{alice.PublicKey, reward + 1}, {bob.PublicKey, -1}}}}, errMalformedCoinbase},
+		{"vote", []Transaction{{Sender: MiningSender, Recipient: bob.PublicKey, Amount: reward, Nonce: 1, Vote: VoteAdd}}, errMalformedCoinbase},
+		{"nonce of another block", []Transaction{{Sender: MiningSender, Recipient: bob.PublicKey, Amount: reward, Nonce: 2}}, errCoinbaseNonce},
+		{"no transactions", nil, errMissingCoinbase},
+		{"no coinbase", []Transaction{pay}, errMissingCoinbase},
+		{"misplaced coinbase", []Transaction{coinbase(reward, 0), pay}, errMisplacedCoinbase},
+		{"two coinbases", []Transaction{coinbase(reward, 0), coinbase(reward, 0)}, errMisplacedCoinbase},
+		{"genesis transaction", []Transaction{genesisTransaction(alice.PublicKey, 1), coinbase(reward, 0)}, errGenesisTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verification.VerifyCoinbase(Block{Index: 1, Transactions: tt.transactions})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("VerifyCoinbase() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestBlockFees(t *testing.T) {
+	block := Block{Transactions: []Transaction{
+		{Sender: "a", Fee: 2},
+		{Sender: "b", Fee: 3},
+		{Sender: MiningSender, Amount: 100, Fee: 1000},
+	}}
+	if fees, err := BlockFees(block); err != nil || fees != 5 {
+		t.Errorf("BlockFees() = %d, %v, want 5", fees, err)
+	}
+
+	block.Transactions = append(block.Transactions, Transaction{Sender: "c", Fee: math.MaxInt64 - 4})
+	if _, err := BlockFees(block); !errors.Is(err, errInvalidAmount) {
+		t.Errorf("BlockFees() = %v, want %v", err, errInvalidAmount)
+	}
+}
+
+func TestVerifyTransaction(t *testing.T) {
+	alice, bob := newTestWallet(), newTestWallet()
+	balance := func(string) Amount { return 100 }
+	pay := func(amount, fee Amount) Transaction {
+		return signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: amount, Fee: fee})
+	}
+
+	tests := []struct {
+		name string
+		tx   Transaction
+		want bool
+	}{
+		{"amount and fee cover the balance", pay(90, 10), true},
+		{"fee only", pay(0, 100), true},
+		{"fee exceeds the balance", pay(90, 11), false},
+		{"negative fee", pay(110, -10), false},
+		{"negative amount", pay(-10, 1), false},
+		{"fee overflowing", pay(10, math.MaxInt64), false},
+		{"forged signature", signed(bob, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verification.VerifyTransaction(tt.tx, balance); got != tt.want {
+				t.Errorf("VerifyTransaction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// appendBlock seals a block of the transactions and a coinbase paying the reward and fees on top of the chain.
+func appendBlock(chain []Block, transactions ...Transaction) []Block {
+	index := int64(len(chain))
+	block := Block{
+		PreviousHash: chain[len(chain)-1].Hash(),
+		Index:        index,
+		Timestamp:    NextTimestamp(chain),
+		Transactions: transactions,
+	}
+	fees, _ := BlockFees(block)
+	block.Transactions = append(block.Transactions, Transaction{
+		Sender:    MiningSender,
+		Recipient: "miner",
+		Amount:    Params.Policy.Reward(index) + fees,
+		Nonce:     uint64(index),
+	})
+	Consensus.Prepare(chain, &block)
+	Consensus.Seal(context.Background(), chain, &block)
+	return append(chain[:len(chain):len(chain)], block)
+}
+
+func TestVerifyChainNonces(t *testing.T) {
+	defer setupRegtest(t)()
+	alice, bob := newTestWallet(), newTestWallet()
+	GenesisBlock = Genesis{ChainID: "regtest", Allocations: map[string]Amount{alice.PublicKey: 100}}.Block()
+	pay := func(nonce uint64) Transaction {
+		return signed(alice, Transaction{Sender: alice.PublicKey, Recipient: bob.PublicKey, Amount: 10, Fee: 1, Nonce: nonce})
+	}
+	chain := appendBlock([]Block{GenesisBlock}, pay(0))
+
+	tests := []struct {
+		name  string
+		chain []Block
+		err   error
+	}{
+		{"next nonce", appendBlock(chain, pay(1)), nil},
+		{"consecutive nonces", appendBlock(chain, pay(1), pay(2)), nil},
+		{"replayed transaction", appendBlock(chain, pay(0)), errInvalidNonce},
+		{"skipped nonce", appendBlock(chain, pay(2)), errInvalidNonce},
+		{"nonces out of order", appendBlock(chain, pay(2), pay(1)), errInvalidNonce},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verification.VerifyChain(tt.chain)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("VerifyChain() = %v, want %v", err, tt.err)
+			}
+			var chainErr *ChainError
+			if err != nil && (!errors.As(err, &chainErr) || chainErr.Index != 2) {
+				t.Errorf("VerifyChain() = %v, want an error of block 2", err)
+			}
+		})
+	}
+}
@@ -91,7 +91,7 @@ func (w *Wallet) LoadKeys() bool {
 	return true
 }
 
-func (w *Wallet) SignTransaction(sender, recipient string, amount float64) string {
+func (w *Wallet) Sign(payload string) string {
 	privateKey, err := base64.StdEncoding.DecodeString(w.PrivateKey)
 	if err != nil {
 		panic(err)
@@ -102,7 +102,7 @@ func (w *Wallet) SignTransaction(sender, recipient string, amount float64) strin
 		panic(err)
 	}
 
-	hash := sha256.Sum256([]byte(fmt.Sprintf("%s%s%f", sender, recipient, amount)))
+	hash := sha256.Sum256([]byte(payload))
 	signature, err := rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, hash[:])
 	if err != nil {
 		panic(err)
@@ -111,22 +111,37 @@ func (w *Wallet) SignTransaction(sender, recipient string, amount float64) strin
 	return base64.StdEncoding.EncodeToString(signature)
 }
 
-func (w Wallet) VerifyTransaction(transaction Transaction) bool {
-	publicKey, err := base64.StdEncoding.DecodeString(transaction.Sender)
+func (w *Wallet) SignTransaction(transaction Transaction) string {
+	return w.Sign(transaction.SigningPayload())
+}
+
+// VerifySignature reports whether signature is a valid signature of the payload by the public key.
+func (w Wallet) VerifySignature(publicKey, payload, signature string) bool {
+	rawPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
 	if err != nil {
-		panic(err)
+		return false
 	}
 
-	verifier, err := x509.ParsePKCS1PublicKey(publicKey)
+	verifier, err := x509.ParsePKCS1PublicKey(rawPublicKey)
 	if err != nil {
-		panic(err)
+		return false
 	}
 
-	signature, err := base64.StdEncoding.DecodeString(transaction.Signature)
+	rawSignature, err := base64.StdEncoding.DecodeString(signature)
 	if err != nil {
-		panic(err)
+		return false
 	}
 
-	hash := sha256.Sum256([]byte(fmt.Sprintf("%s%s%f", transaction.Sender, transaction.Recipient, transaction.Amount)))
-	return rsa.VerifyPKCS1v15(verifier, crypto.SHA256, hash[:], signature) == nil
+	hash := sha256.Sum256([]byte(payload))
+	return rsa.VerifyPKCS1v15(verifier, crypto.SHA256, hash[:], rawSignature) == nil
+}
+
+func (w Wallet) VerifyTransaction(transaction Transaction) bool {
+	if transaction.Multisig != nil {
+		return w.VerifyMultisig(transaction)
+	}
+	if len(transaction.Signatures) > 0 {
+		return false
+	}
+	return w.VerifySignature(transaction.Sender, transaction.SigningPayload(), transaction.Signature)
 }
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"math/big"
+)
+
+// ChainWork returns the cumulative work of all blocks of the chain.
+func ChainWork(chain []Block) *big.Int {
+	work := new(big.Int)
+	for _, b := range chain {
+		work.Add(work, Consensus.Work(b))
+	}
+	return work
+}
+
+// HeavierChain reports whether the chain with work and tipHash wins over the chain with otherWork and otherTipHash.
+// Chains with equal work are ordered by the hash of their last block, the lower one wins.
+func HeavierChain(work *big.Int, tipHash string, otherWork *big.Int, otherTipHash string) bool {
+	if c := work.Cmp(otherWork); c != 0 {
+		return c > 0
+	}
+	return tipHash < otherTipHash
+}
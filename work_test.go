@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestHeavierChain(t *testing.T) {
+	tests := []struct {
+		name                  string
+		work, otherWork       int64
+		tipHash, otherTipHash string
+		want                  bool
+	}{
+		{"more work", 10, 9, "b", "a", true},
+		{"less work", 9, 10, "a", "b", false},
+		{"equal work and lower tip hash", 10, 10, "a", "b", true},
+		{"equal work and higher tip hash", 10, 10, "b", "a", false},
+		{"same chain", 10, 10, "a", "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HeavierChain(big.NewInt(tt.work), tt.tipHash, big.NewInt(tt.otherWork), tt.otherTipHash); got != tt.want {
+				t.Errorf("HeavierChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProofOfWorkSelectFork(t *testing.T) {
+	defer func(consensus ConsensusEngine) { Consensus = consensus }(Consensus)
+	Consensus = ProofOfWork{}
+
+	genesis := Block{Difficulty: 1}
+	long := []Block{genesis, {Index: 1, Difficulty: 1}, {Index: 2, Difficulty: 1}, {Index: 3, Difficulty: 1}}
+	heavy := []Block{genesis, {Index: 1, Difficulty: 4}}
+
+	if !(ProofOfWork{}).SelectFork(long, heavy) {
+		t.Error("the longer chain with less work wins over the heavier one")
+	}
+	if (ProofOfWork{}).SelectFork(heavy, long) {
+		t.Error("the heavier chain is replaced by a longer one with less work")
+	}
+	if ChainWork(heavy).Cmp(big.NewInt(2+16)) != 0 {
+		t.Errorf("ChainWork() = %s, want 18", ChainWork(heavy))
+	}
+}